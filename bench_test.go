@@ -0,0 +1,80 @@
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+//每次读取都持有互斥锁，模拟快照之前每个getter都调用Lock()的实现，作为对比
+type mutexConfig struct {
+	Configer
+	mu sync.Mutex
+}
+
+func (c *mutexConfig) Int(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Configer.Int(key)
+}
+
+func (c *mutexConfig) String(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Configer.String(key)
+}
+
+func benchmarkParallelRead(b *testing.B, c Configer) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if v, err := c.Int("mysql::port"); err != nil || v != 3306 {
+				b.Error("Get int failed:", v, err)
+				return
+			}
+			if v := c.String("mysql::addr"); v != "127.0.0.1" {
+				b.Error("Get string failed:", v)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkParallelRead(b *testing.B) {
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name+"/snapshot", func(b *testing.B) {
+			benchmarkParallelRead(b, config)
+		})
+		b.Run(name+"/mutex", func(b *testing.B) {
+			benchmarkParallelRead(b, &mutexConfig{Configer: config})
+		})
+	}
+}
+
+//读取的同时有写入，写入为复制快照，读取不会被阻塞
+func BenchmarkParallelReadWithWrites(b *testing.B) {
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			done := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						config.Set("mysql::user", "root")
+					}
+				}
+			}()
+			benchmarkParallelRead(b, config)
+			close(done)
+		})
+	}
+}
@@ -1,20 +1,29 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
 //configer 接口，提供操作配置文件的一系列接口
 type Configer interface {
-	Set(key, val string) error       //给配置文件的某个字段设置值，支持sec::key的方式选择key值
-	String(key string) string        //返回指定key的Val值得string格式，key支持sec::key的方式
-	Strings(key string) []string     //返回指定key值得Val的切片
-	Int(key string) (int, error)     //返回指定key对应val的int值
-	Int64(key string) (int64, error) //返回指定key对应val得int64值
+	Set(key, val string) error                  //给配置文件的某个字段设置值，支持sec::key的方式选择key值
+	SetValue(key string, val interface{}) error //设置任意类型的值，JSON保存原始类型，INI转换为字符串
+	String(key string) string                   //返回指定key的Val值得string格式，key支持sec::key的方式，值中的${...}引用会被展开
+	RawString(key string) string                //返回指定key的原始值，不展开${...}引用，也不解析file://等引用
+	GetString(key string) (string, error)       //与String相同，但插值或者file://等引用解析失败时返回错误
+	Strings(key string) []string                //返回指定key值得Val的切片
+	Int(key string) (int, error)                //返回指定key对应val的int值
+	Int64(key string) (int64, error)            //返回指定key对应val得int64值
 	Bool(key string) (bool, error)
 	Float(key string) (float64, error)
 	DefaultString(key, defaultVal string) string //返回指定key的val的值，若key对应的val为空，给该key对应的val设置我defaultVal
@@ -23,23 +32,50 @@ type Configer interface {
 	DefaultInt64(key string, defaultVal int64) int64
 	DefaultBool(key string, defaultVal bool) bool
 	DefaultFloat(key string, defaultVal float64) float64
-	GetInerfaceVal(key string) (interface{}, error)       //返回给定key的val，并将val转型为interface{}类型
-	GetSection(section string) (map[string]string, error) //返回某个section下的全部配置
-	SaveConfigFile(filename string) error                 //将配置信息保存到文件
-	GetCfgData() interface{}
+	Duration(key string) (time.Duration, error) //返回指定key的时长，支持"1h30m"以及表示秒数的数字
+	DefaultDuration(key string, defaultVal time.Duration) time.Duration
+	Time(key string, layouts ...string) (time.Time, error) //按layouts依次解析时间，默认使用time.RFC3339
+	Bytes(key string) (int64, error)                       //返回指定key表示的字节数，支持"512MB"、"1GiB"等格式
+	DefaultBytes(key string, defaultVal int64) int64
+	GetInerfaceVal(key string) (interface{}, error)       //返回给定key的val，并将val转型为interface{}类型，map和slice为副本，其中的引用和ENC(...)已展开
+	GetSection(section string) (map[string]string, error) //返回某个section下的全部配置，返回值为副本，与String一样展开引用并解密
+	Delete(key string) error                              //删除配置项及其注释，key支持sec::key的方式
+	DeleteSection(section string) error                   //删除section及其下的全部配置项和注释
+	Keys(section string) []string                         //返回section下全部配置项的名字，按字母排序，section为空时表示DEFAULT_SECTION
+	Sections() []string                                   //返回全部section的名字，DEFAULT_SECTION在最前，其余按字母排序
+	HasSection(section string) bool
+	Comment(key string) string                                    //返回配置项的注释，key支持sec::key的方式
+	SetComment(key, comment string) error                         //设置配置项的注释，comment为空时删除注释
+	SectionComment(section string) string                         //返回section的注释
+	SetSectionComment(section, comment string) error              //设置section的注释，comment为空时删除注释
+	SaveConfigFile(filename string) error                         //将配置信息保存到文件，先写入临时文件再替换，保留原文件的权限和属主
+	WriteTo(w io.Writer) (int64, error)                           //将配置信息按文件格式写入w
+	GetCfgData() interface{}                                      //返回全部配置数据的副本，值为配置文件中的原始值
+	Snapshot() Configer                                           //返回当前配置的只读快照，之后的修改和重新加载不影响快照
+	View(fn func(Configer) error) error                           //在同一个快照上执行fn，fn中的多次读取结果一致
+	Begin() Tx                                                    //开始一个事务，批量修改后一起提交或者放弃
+	Reload(validators ...Validator) error                         //重新解析Parse时的文件，校验通过后替换当前配置
+	Watch(ctx context.Context, keyPattern string) <-chan Event    //订阅匹配keyPattern的配置项的变化
+	OnChange(ctx context.Context, section string, fn func(Event)) //section下的配置项变化时调用fn
 }
 
-//Configer的适配器接口，将配置文件或者数据解析，并返回一个Configer的对象
+//修改只读快照时返回的错误
+var ErrReadOnly = errors.New("config snapshot is read-only")
+
+//Configer的适配器接口，将配置文件或者数据解析，并返回一个Configer的对象，opts保存在返回的对象中
 type Config interface {
-	Parse(filename string) (Configer, error)
-	ParseData(data []byte) (Configer, error)
+	Parse(filename string, opts ...Option) (Configer, error)
+	ParseData(data []byte, opts ...Option) (Configer, error)
+	ParseReader(r io.Reader, opts ...Option) (Configer, error)
+	ParseFS(fsys fs.FS, name string, opts ...Option) (Configer, error) //从fsys(比如embed.FS)中读取name，include等引用也在fsys中查找
 }
 
 //适配器，保存所有注册的config适配器，key值为配置文件类型，比如ini,xml,conf等
 //val 文件类型对用的适配器
 var adapters = make(map[string]Config)
 
-func Register(name string, adapter Config) {
+//注册适配器，exts为该适配器处理的文件扩展名，比如".ini"，Load根据扩展名选择适配器
+func Register(name string, adapter Config, exts ...string) {
 	if adapter == nil {
 		panic("Config: adapter can not be empty")
 	}
@@ -47,29 +83,79 @@ func Register(name string, adapter Config) {
 		panic("CConfig: adapter for name:" + name + "is only allowed to register once")
 	}
 	adapters[name] = adapter
+	for _, ext := range exts {
+		RegisterExtension(ext, name)
+	}
 }
 
 //返回一个新的Configuer对象，adaptername是文件类型 ini/xml/conf ...
-func NewConfig(adaptername, filename string) (Configer, error) {
+func NewConfig(adaptername, filename string, opts ...Option) (Configer, error) {
 	adapter, ok := adapters[adaptername]
 	if !ok {
 		return nil, errors.New("unknown adaptername" + adaptername + ", should register first,then use it")
 	}
-	return adapter.Parse(filename)
+	return adapter.Parse(filename, opts...)
+}
+
+//adapterName为空时根据内容判断适配器，见DetectAdapter
+func NewConfigData(adapterName string, data []byte, opts ...Option) (Configer, error) {
+	if len(adapterName) == 0 {
+		adapterName = DetectAdapter(data)
+	}
+	adapter, ok := adapters[adapterName]
+	if !ok {
+		return nil, errors.New("unknown adaptername" + adapterName + ", should register first,then use it")
+	}
+	return adapter.ParseData(data, opts...)
+}
+
+//从r中读取配置，adapterName为空时根据内容判断适配器
+func NewConfigReader(adapterName string, r io.Reader, opts ...Option) (Configer, error) {
+	if len(adapterName) == 0 {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewConfigData("", data, opts...)
+	}
+	adapter, ok := adapters[adapterName]
+	if !ok {
+		return nil, errors.New("unknown adaptername" + adapterName + ", should register first,then use it")
+	}
+	return adapter.ParseReader(r, opts...)
 }
 
-func NewConfigData(adapterName string, data []byte) (Configer, error) {
+//从fsys中读取配置文件name，fsys可以是embed.FS、zip.Reader或者fstest.MapFS等
+func NewConfigFS(adapterName string, fsys fs.FS, name string, opts ...Option) (Configer, error) {
 	adapter, ok := adapters[adapterName]
 	if !ok {
 		return nil, errors.New("unknown adaptername" + adapterName + ", should register first,then use it")
 	}
-	return adapter.ParseData(data)
+	return adapter.ParseFS(fsys, name, opts...)
+}
+
+//计算配置项的最终值：先展开${...}引用，再解密ENC(...)，最后解析file://、env://等scheme引用
+func evalValue(key, val string, lookup lookupFunc, o *Options) (string, error) {
+	val, err := interpolate(key, val, lookup, o)
+	if err != nil {
+		return "", err
+	}
+	return finishValue(val)
+}
+
+//对展开引用后的值解密并解析scheme引用，被引用的配置项同样经过这一步
+func finishValue(val string) (string, error) {
+	val, err := Decrypt(val)
+	if err != nil {
+		return "", err
+	}
+	return resolveValue(val)
 }
 
 func ToString(in interface{}) string {
 	switch out := in.(type) {
 	case time.Time:
-		return out.Format("2016-02-05")
+		return out.Format(time.RFC3339)
 	case string:
 		return out
 	case fmt.Stringer:
@@ -78,40 +164,146 @@ func ToString(in interface{}) string {
 		return out.Error()
 	case float64:
 		return strconv.FormatFloat(out, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(out), 'f', -1, 32)
 	}
 	if val := reflect.ValueOf(in); val.Kind() == reflect.String {
 		return val.String()
 	}
-	return fmt.Sprintf("%s", in)
+	return fmt.Sprint(in)
 }
 
+//解析bool值，字符串不区分大小写，支持true/false、yes/no、on/off、t/f、y/n，
+//数字(包括数字字符串以及任意整数、浮点类型)只接受0和1
 func ParseBool(in interface{}) (out bool, err error) {
-	if in != nil {
-		switch v := in.(type) {
-		case bool:
-			return v, nil
-		case string:
-			switch v {
-			case "1", "t", "T", "true", "TRUE", "True", "YES", "yes", "Yes", "Y", "y", "ON", "on", "On":
-				return true, nil
-			case "0", "f", "F", "false", "FALSE", "False", "NO", "no", "No", "N", "n", "OFF", "off", "Off":
-				return false, nil
-			}
-		case int8, int32, int64:
-			str := fmt.Sprintf("%s", v)
-			if str == "1" {
-				return true, nil
-			} else if str == "0" {
-				return false, nil
-			}
-		case float64:
-			if v == 1 {
-				return true, nil
-			} else if v == 0 {
-				return false, nil
+	switch v := in.(type) {
+	case nil:
+		return false, fmt.Errorf("parsing <nil>: invalid syntax")
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "1", "t", "true", "yes", "y", "on":
+			return true, nil
+		case "0", "f", "false", "no", "n", "off":
+			return false, nil
+		}
+	}
+	if f, err := toFloat64(in); err == nil {
+		switch f {
+		case 1:
+			return true, nil
+		case 0:
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("parsing %q: invalid syntax", ToString(in))
+}
+
+//解析时长，字符串支持time.ParseDuration的格式，不带单位的数字表示秒数
+func ParseDuration(in interface{}) (time.Duration, error) {
+	switch v := in.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return secondsToDuration(in, f)
+		}
+		return time.ParseDuration(v)
+	}
+	f, err := toFloat64(in)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %v: invalid duration", in)
+	}
+	return secondsToDuration(in, f)
+}
+
+//将秒数转换为时长，拒绝Inf、NaN以及超出time.Duration范围的值
+func secondsToDuration(in interface{}, f float64) (time.Duration, error) {
+	ns := f * float64(time.Second)
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns <= math.MinInt64 {
+		return 0, fmt.Errorf("parsing %v: duration out of range", in)
+	}
+	return time.Duration(ns), nil
+}
+
+//按layouts依次尝试解析时间，layouts为空时使用time.RFC3339
+func ParseTime(in interface{}, layouts ...string) (time.Time, error) {
+	switch v := in.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if len(layouts) == 0 {
+			layouts = []string{time.RFC3339}
+		}
+		var err error
+		for _, layout := range layouts {
+			var t time.Time
+			if t, err = time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
 			}
 		}
-		return false, fmt.Errorf("parsing %q:invalid syntax", in)
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("parsing %v: invalid time", in)
+}
+
+//字节数单位，KB、MB等为10进制单位，KiB、MiB等为2进制单位，K、M等同KB、MB
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+}
+
+//解析字节数，比如"512MB"、"1.5GiB"、"1024"，单位不区分大小写
+func ParseBytes(in interface{}) (int64, error) {
+	switch v := in.(type) {
+	case string:
+		s := strings.TrimSpace(v)
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i < 0 {
+			i = len(s)
+		}
+		num, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: invalid byte size", v)
+		}
+		unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+		if !ok {
+			return 0, fmt.Errorf("parsing %q: unknown byte size unit", v)
+		}
+		size := num * unit
+		if size >= math.MaxInt64 {
+			return 0, fmt.Errorf("parsing %q: byte size out of range", v)
+		}
+		return int64(size), nil
+	}
+	//与字符串一致，负数不是合法的字节数
+	v, err := toInt64(in)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("parsing %v: invalid byte size", in)
 	}
-	return false, fmt.Errorf("parsing <nil>: invalid syntax")
+	return v, nil
 }
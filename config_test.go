@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+var typesIni = `
+timeout = 1h30m
+retry = 90
+started = 2017-08-28T10:00:00Z
+day = 2017-08-28
+cache = 512MB
+heap = 1.5GiB
+bad = 12XB
+`
+
+var typesJson = `{
+	"timeout": "1h30m",
+	"retry": 90,
+	"started": "2017-08-28T10:00:00Z",
+	"day": "2017-08-28",
+	"cache": "512MB",
+	"heap": "1.5GiB",
+	"bad": "12XB"
+}`
+
+func TestTypedGetters(t *testing.T) {
+	for name, data := range map[string]string{"ini": typesIni, "json": typesJson} {
+		config, err := NewConfigData(name, []byte(data))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if val, err := config.Duration("timeout"); err != nil || val != 90*time.Minute {
+			t.Error(name, "Get duration failed:", val, err)
+		}
+		if val := config.DefaultDuration("retry", time.Second); val != 90*time.Second {
+			t.Error(name, "Get bare seconds failed:", val)
+		}
+		if val := config.DefaultDuration("missing", time.Second); val != time.Second {
+			t.Error(name, "Get default duration failed:", val)
+		}
+		if val, err := config.Time("started"); err != nil || !val.Equal(time.Date(2017, 8, 28, 10, 0, 0, 0, time.UTC)) {
+			t.Error(name, "Get time failed:", val, err)
+		}
+		if _, err := config.Time("day"); err == nil {
+			t.Error(name, "date should not match RFC3339")
+		}
+		if val, err := config.Time("day", time.RFC3339, "2006-01-02"); err != nil || val.Day() != 28 {
+			t.Error(name, "Get time with layout failed:", val, err)
+		}
+		if val, err := config.Bytes("cache"); err != nil || val != 512e6 {
+			t.Error(name, "Get bytes failed:", val, err)
+		}
+		if val := config.DefaultBytes("heap", 0); val != 3<<29 {
+			t.Error(name, "Get binary bytes failed:", val)
+		}
+		if val := config.DefaultBytes("bad", 1); val != 1 {
+			t.Error(name, "Get default bytes failed:", val)
+		}
+	}
+}
+
+func TestToStringTime(t *testing.T) {
+	tm := time.Date(2017, 8, 28, 10, 0, 0, 0, time.UTC)
+	if val := ToString(tm); val != "2017-08-28T10:00:00Z" {
+		t.Error("format time failed:", val)
+	}
+}
+
+func TestParseDurationRange(t *testing.T) {
+	for _, in := range []interface{}{"Inf", "-Inf", "NaN", "1e20", "-1e20", 1e20, json.Number("1e20"), math.Inf(1)} {
+		if val, err := ParseDuration(in); err == nil {
+			t.Errorf("duration %v should fail, got %v", in, val)
+		}
+	}
+	if val, err := ParseDuration("-1.5"); err != nil || val != -1500*time.Millisecond {
+		t.Error("negative duration failed:", val, err)
+	}
+}
+
+func TestNegativeBytes(t *testing.T) {
+	for name, data := range map[string]string{"ini": "size = -1\n", "json": `{"size": -1}`} {
+		config, err := NewConfigData(name, []byte(data))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if val, err := config.Bytes("size"); err == nil {
+			t.Error(name, "negative byte size should fail:", val)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+//配置快照需要提供的方法，T为快照类型，P为*T
+type configState[T any] interface {
+	*T
+	clone() *T                     //复制快照，返回的副本可以直接修改
+	flatten() map[string]flatValue //展开配置项，用于计算Watch的事件
+	options() *Options
+}
+
+//IniConfigContainer和JsonCfgContainer共用的快照管理：读取配置时不加锁，直接读取当前的快照；
+//修改配置时持有写锁，在快照的副本上修改后整体替换，因此读取方总是看到某一次修改完成后的完整配置
+type container[T any, P configState[T]] struct {
+	state    atomic.Pointer[T]
+	readOnly bool         //Snapshot返回的只读快照，所有修改返回ErrReadOnly
+	file     *fileTracker //配置来自的文件，由Parse记录，用于保存时检查文件是否被其他进程修改
+	watch    *watchHub
+	mu       sync.Mutex //写锁，只在修改配置时持有，读取不加锁
+}
+
+//用快照s创建同类型的配置，返回配置以及其中的container
+type deriveFunc[T any, P configState[T]] func(s P) (Configer, *container[T, P])
+
+//解析filename的内容data，返回新的快照
+type parseFunc[T any, P configState[T]] func(filename string, data []byte, o Options) (P, error)
+
+func (c *container[T, P]) init(s P) {
+	c.watch = newWatchHub()
+	c.state.Store((*T)(s))
+}
+
+func (c *container[T, P]) load() P {
+	return P(c.state.Load())
+}
+
+//在当前快照的副本上执行fn，fn成功后用副本替换当前快照，fn返回错误时配置不变
+func (c *container[T, P]) update(fn func(s P) error) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := P(c.load().clone())
+	if err := fn(s); err != nil {
+		return err
+	}
+	c.store(s)
+	return nil
+}
+
+//替换当前快照并通知订阅者，调用方需持有写锁
+func (c *container[T, P]) store(s P) {
+	old := P(c.state.Swap((*T)(s)))
+	if c.watch.active() {
+		c.watch.publish(diffFlat(old.flatten(), s.flatten()))
+	}
+}
+
+//用parse重新解析Parse时的文件，依次执行validators，全部通过后替换当前快照，解析或者校验失败时配置不变；
+//返回变化的配置项，同时通知给Watch和OnChange的订阅者
+func (c *container[T, P]) reload(validators []Validator, parse parseFunc[T, P], derive deriveFunc[T, P]) ([]Event, error) {
+	if c.readOnly {
+		return nil, ErrReadOnly
+	}
+	if c.file == nil {
+		return nil, errors.New("config was not loaded from a file")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file.Lock()
+	defer c.file.Unlock()
+	data, file, err := readTrackedFile(c.file.filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parse(c.file.filename, data, *c.load().options())
+	if err != nil {
+		return nil, err
+	}
+	snap, sc := derive(s)
+	sc.readOnly = true
+	sc.file = c.file
+	for _, validate := range validators {
+		if err = validate(snap); err != nil {
+			return nil, err
+		}
+	}
+	events := diffFlat(P(c.state.Swap((*T)(s))).flatten(), s.flatten())
+	if c.watch.active() {
+		c.watch.publish(events)
+	}
+	c.file.fp = file.fp
+	return events, nil
+}
+
+//返回匹配keyPattern的配置项的变化，keyPattern按KeySeparator分段，每段支持path.Match的通配符，
+//最后一段为"**"时匹配任意多段，比如 mysql::* 、 mysql::** 。ctx结束时取消订阅并关闭channel
+func (c *container[T, P]) Watch(ctx context.Context, keyPattern string) <-chan Event {
+	return c.watch.watch(ctx, keyPattern, c.load().options())
+}
+
+//section下的配置项变化时在单独的goroutine中依次调用fn，ctx结束时取消订阅
+func (c *container[T, P]) OnChange(ctx context.Context, section string, fn func(Event)) {
+	c.watch.onChange(ctx, section, fn, c.load().options())
+}
+
+//返回当前配置的只读快照，快照与配置共享不可变的数据，不需要复制；self为c所在的配置
+func (c *container[T, P]) snapshot(self Configer, derive deriveFunc[T, P]) Configer {
+	if c.readOnly {
+		return self
+	}
+	snap, sc := derive(c.load())
+	sc.readOnly = true
+	sc.file = c.file
+	return snap
+}
+
+//事务在当前快照的副本上修改，提交时当前快照仍然是事务开始时的快照才替换，否则返回ErrTxConflict
+func (c *container[T, P]) begin(derive deriveFunc[T, P]) Tx {
+	base := c.load()
+	work, wc := derive(P(base.clone()))
+	wc.file = c.file
+	return newTx(work, func(save func() error) error {
+		if c.readOnly {
+			return ErrReadOnly
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.load() != base {
+			return ErrTxConflict
+		}
+		if err := save(); err != nil {
+			return err
+		}
+		c.store(wc.load())
+		return nil
+	})
+}
+
+func (c *container[T, P]) tracker() *fileTracker {
+	return c.file
+}
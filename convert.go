@@ -0,0 +1,323 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//所有适配器共用的类型转换，输入为配置项的值：INI中为string，JSON中为
+//string、float64、bool、[]interface{}、map[string]interface{}等
+
+var ErrInvalidValue = errors.New("val is not valid")
+
+//保存注册的转换函数，key为目标类型，val为func(interface{}) (T, error)
+var (
+	converterMu sync.RWMutex
+	converters  = make(map[reflect.Type]interface{})
+)
+
+//注册类型T的转换函数，Get[T]和GetOr[T]使用它将配置项的值转换为T，
+//与适配器一样每个类型只允许注册一次
+func RegisterConverter[T any](fn func(in interface{}) (T, error)) {
+	if fn == nil {
+		panic("Config: converter can not be empty")
+	}
+	t := typeOf[T]()
+	converterMu.Lock()
+	defer converterMu.Unlock()
+	if _, ok := converters[t]; ok {
+		panic("Config: converter for type:" + t.String() + " is only allowed to register once")
+	}
+	converters[t] = fn
+}
+
+//返回指定key的值并转换为T，key支持sec::key的方式。
+//T没有注册转换函数时，值本身是T或者*T实现了encoding.TextUnmarshaler也可以转换
+func Get[T any](c Configer, key string) (T, error) {
+	var out T
+	val, err := c.GetInerfaceVal(key)
+	if err != nil {
+		return out, err
+	}
+	if out, err = convert[T](val); err != nil {
+		return out, fmt.Errorf("key %s: %w", key, err)
+	}
+	return out, nil
+}
+
+//与Get相同，key不存在或者转换失败时返回defaultVal
+func GetOr[T any](c Configer, key string, defaultVal T) T {
+	v, err := Get[T](c, key)
+	if err != nil {
+		return defaultVal
+	}
+	return v
+}
+
+func convert[T any](val interface{}) (T, error) {
+	var out T
+	t := typeOf[T]()
+	converterMu.RLock()
+	fn, ok := converters[t]
+	converterMu.RUnlock()
+	if ok {
+		return fn.(func(interface{}) (T, error))(val)
+	}
+
+	if v, ok := val.(T); ok {
+		return v, nil
+	}
+	if u, ok := interface{}(&out).(encoding.TextUnmarshaler); ok {
+		if s, ok := val.(string); ok {
+			err := u.UnmarshalText([]byte(s))
+			return out, err
+		}
+	}
+	return out, fmt.Errorf("no converter for type %s", t)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+//转换为int64，支持整数、整数值的浮点数以及它们的字符串形式(比如"3306"、"3306.0"、"1e3")，
+//浮点数有小数部分、超出int64的范围时返回错误
+func toInt64(in interface{}) (int64, error) {
+	switch v := in.(type) {
+	case string:
+		return parseInt64(v)
+	case json.Number:
+		return parseInt64(string(v))
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := toUint64(v)
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v overflows int64", in)
+		}
+		return int64(u), nil
+	case float32:
+		return floatToInt64(float64(v))
+	case float64:
+		return floatToInt64(v)
+	}
+	return 0, ErrInvalidValue
+}
+
+func parseInt64(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if isRangeErr(err) {
+		return 0, fmt.Errorf("value %s overflows int64", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: invalid integer", s)
+	}
+	return floatToInt64(f)
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	//float64(math.MaxInt64)等于2^63，已经超出int64的范围
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %v overflows int64", f)
+	}
+	return int64(f), nil
+}
+
+func isRangeErr(err error) bool {
+	ne, ok := err.(*strconv.NumError)
+	return ok && ne.Err == strconv.ErrRange
+}
+
+//转换为uint64，支持的格式同toInt64，负数返回错误
+func toUint64(in interface{}) (uint64, error) {
+	switch v := in.(type) {
+	case string:
+		return parseUint64(v)
+	case json.Number:
+		return parseUint64(string(v))
+	case uint:
+		return uint64(v), nil
+	case uint8:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float32:
+		return floatToUint64(float64(v))
+	case float64:
+		return floatToUint64(v)
+	}
+	i, err := toInt64(in)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("value %v overflows unsigned integer", in)
+	}
+	return uint64(i), nil
+}
+
+func parseUint64(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err == nil {
+		return u, nil
+	}
+	if isRangeErr(err) {
+		return 0, fmt.Errorf("value %s overflows uint64", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: invalid integer", s)
+	}
+	return floatToUint64(f)
+}
+
+func floatToUint64(f float64) (uint64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	if f < 0 || f >= math.MaxUint64 {
+		return 0, fmt.Errorf("value %v overflows uint64", f)
+	}
+	return uint64(f), nil
+}
+
+//转换为float64，支持整数、浮点数以及它们的字符串形式
+func toFloat64(in interface{}) (float64, error) {
+	switch v := in.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: invalid number", v)
+		}
+		return f, nil
+	case json.Number:
+		return toFloat64(string(v))
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := toUint64(v)
+		return float64(u), nil
+	}
+	i, err := toInt64(in)
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
+
+func toInt(in interface{}) (int, error) {
+	return intConverter[int](strconv.IntSize)(in)
+}
+
+//返回位数为bits的有符号整数的转换函数，超出范围时返回错误
+func intConverter[T int | int8 | int16 | int32 | int64](bits int) func(interface{}) (T, error) {
+	return func(in interface{}) (T, error) {
+		v, err := toInt64(in)
+		if err != nil {
+			return 0, err
+		}
+		if bits < 64 && (v < -1<<(bits-1) || v > 1<<(bits-1)-1) {
+			return 0, fmt.Errorf("value %v overflows int%d", in, bits)
+		}
+		return T(v), nil
+	}
+}
+
+//返回位数为bits的无符号整数的转换函数，超出范围时返回错误
+func uintConverter[T uint | uint8 | uint16 | uint32 | uint64](bits int) func(interface{}) (T, error) {
+	return func(in interface{}) (T, error) {
+		v, err := toUint64(in)
+		if err != nil {
+			return 0, err
+		}
+		if bits < 64 && v > 1<<bits-1 {
+			return 0, fmt.Errorf("value %v overflows uint%d", in, bits)
+		}
+		return T(v), nil
+	}
+}
+
+func toFloat32(in interface{}) (float32, error) {
+	v, err := toFloat64(in)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(v) > math.MaxFloat32 && !math.IsInf(v, 0) {
+		return 0, fmt.Errorf("value %v overflows float32", in)
+	}
+	return float32(v), nil
+}
+
+func toStrings(in interface{}) ([]string, error) {
+	switch v := in.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, vv := range v {
+			out = append(out, ToString(vv))
+		}
+		return out, nil
+	}
+	return nil, ErrInvalidValue
+}
+
+func init() {
+	RegisterConverter(func(in interface{}) (string, error) {
+		if in == nil {
+			return "", ErrInvalidValue
+		}
+		return ToString(in), nil
+	})
+	RegisterConverter(ParseBool)
+	RegisterConverter(intConverter[int](strconv.IntSize))
+	RegisterConverter(intConverter[int8](8))
+	RegisterConverter(intConverter[int16](16))
+	RegisterConverter(intConverter[int32](32))
+	RegisterConverter(intConverter[int64](64))
+	RegisterConverter(uintConverter[uint](strconv.IntSize))
+	RegisterConverter(uintConverter[uint8](8))
+	RegisterConverter(uintConverter[uint16](16))
+	RegisterConverter(uintConverter[uint32](32))
+	RegisterConverter(uintConverter[uint64](64))
+	RegisterConverter(toFloat32)
+	RegisterConverter(toFloat64)
+	RegisterConverter(ParseDuration)
+	RegisterConverter(func(in interface{}) (time.Time, error) {
+		return ParseTime(in)
+	})
+	RegisterConverter(toStrings)
+}
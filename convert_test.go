@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return ErrInvalidValue
+	}
+	return nil
+}
+
+type endpoint struct {
+	host string
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if val, err := Get[uint16](config, "mysql::port"); err != nil || val != 3306 {
+			t.Error(name, "Get uint16 failed:", val, err)
+		}
+		if val, err := Get[int8](config, "mysql::port"); err == nil {
+			t.Error(name, "int8 overflow not detected:", val)
+		}
+		if val, err := Get[float32](config, "float"); err != nil || val != 3.1415 {
+			t.Error(name, "Get float32 failed:", val, err)
+		}
+		if val, err := Get[string](config, "mysql::addr"); err != nil || val != "127.0.0.1" {
+			t.Error(name, "Get string failed:", val, err)
+		}
+		if val, err := Get[bool](config, "IsOpen"); err != nil || val {
+			t.Error(name, "Get bool failed:", val, err)
+		}
+		if _, err := Get[int](config, "mysql::missing"); err == nil {
+			t.Error(name, "missing key should fail")
+		}
+		if val := GetOr[time.Duration](config, "mysql::timeout", time.Second); val != time.Second {
+			t.Error(name, "GetOr failed:", val)
+		}
+	}
+}
+
+func TestGetUserType(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("level = info\nendpoint = db:3306\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := Get[level](config, "level"); err != nil || val != 1 {
+		t.Error("Get TextUnmarshaler failed:", val, err)
+	}
+	if _, err := Get[endpoint](config, "endpoint"); err == nil {
+		t.Error("type without converter should fail")
+	}
+
+	RegisterConverter(func(in interface{}) (endpoint, error) {
+		return endpoint{host: strings.Split(ToString(in), ":")[0]}, nil
+	})
+	if val, err := Get[endpoint](config, "endpoint"); err != nil || val.host != "db" {
+		t.Error("Get registered type failed:", val, err)
+	}
+}
+
+func TestGetErrorChain(t *testing.T) {
+	config, err := NewConfigData("json", []byte(`{"obj": {"a": 1}}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = Get[int](config, "obj"); !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), "key obj") {
+		t.Error("error chain lost:", err)
+	}
+}
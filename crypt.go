@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+//加密的配置值格式为 ENC(base64(nonce+密文))，使用AES-GCM加密，
+//所有getter读取时会自动解密，RawString返回未解密的原始值
+const (
+	ENC_PREFIX = "ENC("
+	ENC_SUFFIX = ")"
+)
+
+var (
+	ErrNoKeyProvider = errors.New("no key provider for encrypted value")
+	ErrInvalidKey    = errors.New("key should be 16, 24 or 32 bytes, raw or base64 encoded")
+)
+
+//密钥提供者，返回AES密钥，长度为16、24或32字节
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+//KeyProviderFunc 将普通函数适配为KeyProvider
+type KeyProviderFunc func() ([]byte, error)
+
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+var (
+	keyMu       sync.RWMutex
+	keyProvider KeyProvider
+)
+
+//设置解密ENC(...)值使用的密钥提供者，为nil时不解密，读取加密值会返回错误
+func SetKeyProvider(provider KeyProvider) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	keyProvider = provider
+}
+
+//从环境变量读取密钥，环境变量的值为原始密钥或者base64编码的密钥
+func EnvKeyProvider(name string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, errors.New("environment variable " + name + " not set")
+		}
+		return decodeKey([]byte(v))
+	})
+}
+
+//从文件读取密钥，文件内容为原始密钥或者base64编码的密钥，每次解密都会重新读取，
+//因此替换密钥文件后无需重启
+func FileKeyProvider(filename string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return decodeKey(data)
+	})
+}
+
+func decodeKey(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if key, err := base64.StdEncoding.DecodeString(string(data)); err == nil && validKeyLen(len(key)) {
+		return key, nil
+	}
+	if validKeyLen(len(data)) {
+		return data, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+func validKeyLen(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+func newGCM() (cipher.AEAD, error) {
+	keyMu.RLock()
+	provider := keyProvider
+	keyMu.RUnlock()
+	if provider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	key, err := provider.Key()
+	if err != nil {
+		return nil, err
+	}
+	if !validKeyLen(len(key)) {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//判断值是否是ENC(...)格式的加密值
+func IsEncrypted(val string) bool {
+	val = strings.TrimSpace(val)
+	return strings.HasPrefix(val, ENC_PREFIX) && strings.HasSuffix(val, ENC_SUFFIX)
+}
+
+//使用当前的密钥加密明文，返回ENC(...)格式的值
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return ENC_PREFIX + base64.StdEncoding.EncodeToString(sealed) + ENC_SUFFIX, nil
+}
+
+//解密ENC(...)格式的值，其他值原样返回
+func Decrypt(val string) (string, error) {
+	if !IsEncrypted(val) {
+		return val, nil
+	}
+	val = strings.TrimSpace(val)
+	sealed, err := base64.StdEncoding.DecodeString(val[len(ENC_PREFIX) : len(val)-len(ENC_SUFFIX)])
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value failed: %w", err)
+	}
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value failed: %w", err)
+	}
+	return string(plain), nil
+}
+
+//将c中keys对应的明文值原地加密，并通过SaveConfigFile保存到filename，
+//已经加密的值保持不变
+func EncryptValue(c Configer, filename string, keys ...string) error {
+	for _, key := range keys {
+		raw := c.RawString(key)
+		if len(raw) == 0 {
+			return errors.New("key " + key + " not exist or empty")
+		}
+		if IsEncrypted(raw) {
+			continue
+		}
+		enc, err := Encrypt(raw)
+		if err != nil {
+			return err
+		}
+		if err = c.Set(key, enc); err != nil {
+			return err
+		}
+	}
+	return c.SaveConfigFile(filename)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptValue(t *testing.T) {
+	os.Setenv("CONFIG_TEST_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	defer os.Unsetenv("CONFIG_TEST_KEY")
+	SetKeyProvider(EnvKeyProvider("CONFIG_TEST_KEY"))
+	defer SetKeyProvider(nil)
+
+	dir, err := ioutil.TempDir("", "crypt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		filename := filepath.Join(dir, "my."+name)
+		if err = EncryptValue(config, filename, "mysql::passwd"); err != nil {
+			t.Error(name, err)
+			return
+		}
+
+		config, err = NewConfig(name, filename)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if val := config.RawString("mysql::passwd"); !IsEncrypted(val) {
+			t.Error(name, "value not encrypted:", val)
+		}
+		if val := config.String("mysql::passwd"); val != "root" {
+			t.Error(name, "decrypt failed:", val)
+		}
+
+		SetKeyProvider(nil)
+		if _, err = config.GetString("mysql::passwd"); err != ErrNoKeyProvider {
+			t.Error(name, "missing key provider not reported:", err)
+		}
+		SetKeyProvider(EnvKeyProvider("CONFIG_TEST_KEY"))
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	SetKeyProvider(KeyProviderFunc(func() ([]byte, error) {
+		return []byte("0123456789abcdef"), nil
+	}))
+	defer SetKeyProvider(nil)
+	enc, err := Encrypt("root")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	SetKeyProvider(KeyProviderFunc(func() ([]byte, error) {
+		return []byte("fedcba9876543210"), nil
+	}))
+	if _, err = Decrypt(enc); err == nil {
+		t.Error("decrypt with wrong key should fail")
+	}
+}
+
+func TestDecryptErrorChain(t *testing.T) {
+	var corrupt base64.CorruptInputError
+	if _, err := Decrypt(ENC_PREFIX + "not base64!" + ENC_SUFFIX); !errors.As(err, &corrupt) {
+		t.Error("error chain lost:", err)
+	}
+}
+
+func TestDecryptSection(t *testing.T) {
+	SetKeyProvider(KeyProviderFunc(func() ([]byte, error) {
+		return []byte("0123456789abcdef"), nil
+	}))
+	defer SetKeyProvider(nil)
+	enc, err := Encrypt("root")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	os.Setenv("CRYPT_TEST_HOST", "db")
+	defer os.Unsetenv("CRYPT_TEST_HOST")
+	data := map[string]string{
+		"ini":  "[mysql]\npasswd = " + enc + "\nhost = env://CRYPT_TEST_HOST\ndsn = root@${mysql::host}\n",
+		"json": `{"mysql": {"passwd": "` + enc + `", "host": "env://CRYPT_TEST_HOST", "dsn": "root@${mysql::host}", "slaves": ["${mysql::host}"]}}`,
+	}
+	for name, d := range data {
+		config, err := NewConfigData(name, []byte(d))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		sec, err := config.GetSection("mysql")
+		if err != nil || sec["passwd"] != "root" || sec["host"] != "db" || sec["dsn"] != "root@db" {
+			t.Error(name, "GetSection not evaluated:", sec, err)
+		}
+		val, err := config.GetInerfaceVal("mysql")
+		m, _ := val.(map[string]string)
+		if name == "json" {
+			jm, _ := val.(map[string]interface{})
+			m = map[string]string{"passwd": ToString(jm["passwd"])}
+			if slaves, _ := jm["slaves"].([]interface{}); len(slaves) != 1 || slaves[0] != "db" {
+				t.Error(name, "nested array not evaluated:", jm["slaves"])
+			}
+		}
+		if err != nil || m["passwd"] != "root" {
+			t.Error(name, "GetInerfaceVal not evaluated:", val, err)
+		}
+	}
+}
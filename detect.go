@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//扩展名到适配器名的映射，key为小写的扩展名(带"."), val为适配器名
+var extensions = make(map[string]string)
+
+//注册扩展名对应的适配器，后注册的覆盖先注册的，便于将".conf"等通用扩展名指向自定义适配器
+func RegisterExtension(ext, adapterName string) {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	extensions[strings.ToLower(ext)] = adapterName
+}
+
+//根据文件扩展名选择适配器解析文件，扩展名未注册时根据文件内容判断
+func Load(filename string, opts ...Option) (Configer, error) {
+	if name, ok := extensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return NewConfig(name, filename, opts...)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	name := DetectAdapter(data)
+	if len(name) == 0 {
+		return nil, errors.New("can not detect config format of " + filename)
+	}
+	return NewConfig(name, filename, opts...)
+}
+
+//与Load相同，但从fsys中读取
+func LoadFS(fsys fs.FS, name string, opts ...Option) (Configer, error) {
+	if adapterName, ok := extensions[strings.ToLower(path.Ext(name))]; ok {
+		return NewConfigFS(adapterName, fsys, name, opts...)
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	adapterName := DetectAdapter(data)
+	if len(adapterName) == 0 {
+		return nil, errors.New("can not detect config format of " + name)
+	}
+	return NewConfigFS(adapterName, fsys, name, opts...)
+}
+
+//根据内容判断配置格式，返回适配器名，无法判断时返回""：
+//
+//	以{开头：能被encoding/json解析时为json，否则为jsonc
+//	以//或/*开头：jsonc
+//	以<?xml或<开头：xml
+//	以[开头：合法的JSON为json(数组)，第一行是[section]并且不是JSON时为ini
+//	以#、;开头或者第一行包含key=value：ini
+func DetectAdapter(data []byte) string {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case len(data) == 0:
+		return ""
+	case data[0] == '{':
+		if json.Valid(data) {
+			return "json"
+		}
+		return "jsonc"
+	case bytes.HasPrefix(data, []byte("//")), bytes.HasPrefix(data, []byte("/*")):
+		return "jsonc"
+	case data[0] == '<':
+		return "xml"
+	case bytes.HasPrefix(data, NUM_COMMENT), bytes.HasPrefix(data, SEM_COMMENT):
+		return "ini"
+	}
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = bytes.TrimSpace(data[:i])
+	}
+	if bytes.HasPrefix(data, SEC_START) {
+		//JSON数组也以[开头，section头必须在一行内闭合，并且不是合法的JSON
+		if json.Valid(data) {
+			return "json"
+		}
+		if bytes.HasSuffix(line, SEC_END) && !json.Valid(line) {
+			return "ini"
+		}
+		return ""
+	}
+	if bytes.Contains(line, EQUAL) {
+		return "ini"
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	for _, filename := range []string{"my.ini", "my.json"} {
+		config, err := Load(filename)
+		if err != nil {
+			t.Error(filename, err)
+			continue
+		}
+		if val := config.String("mysql::addr"); val != "127.0.0.1" {
+			t.Error(filename, "Get string failed:", val)
+		}
+	}
+
+	//扩展名未注册时根据内容判断
+	filename := filepath.Join(t.TempDir(), "app.settings")
+	if err := ioutil.WriteFile(filename, []byte("\xef\xbb\xbf\n[mysql]\nport = 3306\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err := Load(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("mysql::port"); err != nil || val != 3306 {
+		t.Error("Get sniffed int failed:", val, err)
+	}
+
+	//根据内容判断时include仍然相对于文件所在的目录，并且可以Reload
+	dir := filepath.Join(t.TempDir(), "dir")
+	if err = os.Mkdir(dir, 0755); err != nil {
+		t.Error(err)
+		return
+	}
+	ioutil.WriteFile(filepath.Join(dir, "inc.ini"), []byte("[redis]\nport = 6379\n"), 0644)
+	included := filepath.Join(dir, "app.settings")
+	ioutil.WriteFile(included, []byte("# app\ninclude \"inc.ini\"\n[mysql]\nport = 3306\n"), 0644)
+	if config, err = Load(included); err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("redis::port"); err != nil || val != 6379 {
+		t.Error("Get included int failed:", val, err)
+	}
+	if err = config.Reload(); err != nil {
+		t.Error(err)
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("just some text"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = Load(filename); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
+
+func TestDetectAdapter(t *testing.T) {
+	cases := map[string]string{
+		`{"a": 1}`:                  "json",
+		"  {a: 1, // comment\n}":    "jsonc",
+		"// comment\n{}":            "jsonc",
+		`<?xml version="1.0"?>`:     "xml",
+		"[mysql]\naddr = 127.0.0.1": "ini",
+		"[1, 2]":                    "json",
+		"[\n  1,\n  2,\n]":          "",
+		`["a"]` + "\nx = 1":         "",
+		"[mysql] \r\nport = 1":      "ini",
+		"# comment\na = 1":          "ini",
+		"a = 1":                     "ini",
+		"":                          "",
+		"hello":                     "",
+	}
+	for data, want := range cases {
+		if got := DetectAdapter([]byte(data)); got != want {
+			t.Errorf("detect %q got %q, want %q", data, got, want)
+		}
+	}
+
+	config, err := NewConfigData("", []byte(`{"port": 8080}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("port"); err != nil || val != 8080 {
+		t.Error("Get int failed:", val, err)
+	}
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ChangeType int
+
+const (
+	ChangeAdded ChangeType = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+func (t ChangeType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+//两份配置之间一个配置项的差异
+type Change struct {
+	Type    ChangeType  `json:"type"`
+	Section string      `json:"section"`       //配置项所在的section，不在section下的配置项为DEFAULT_SECTION
+	Key     string      `json:"key"`           //配置项的完整路径，比如 mysql::port 、 mysql::slaves::0
+	Old     interface{} `json:"old,omitempty"` //配置文件中的原始值，Type为ChangeAdded时为nil
+	New     interface{} `json:"new,omitempty"` //配置文件中的原始值，Type为ChangeRemoved时为nil
+	name    string      //去掉section前缀的key
+}
+
+type diffOptions struct {
+	normalize bool
+}
+
+type DiffOption func(*diffOptions)
+
+//比较前统一值的类型，数字按数值比较，true/false忽略大小写，比如INI中的"3306"与JSON中的3306相同；
+//默认按原始值比较，INI中的值都是字符串，因此与JSON中的数字、bool不同
+func WithTypeNormalization() DiffOption {
+	return func(o *diffOptions) {
+		o.normalize = true
+	}
+}
+
+//返回从a到b新增、删除以及修改的配置项，按section和key排序，默认section在最前。
+//key和section与Watch的事件一致，使用各自的KeySeparator和DefaultSection，b的key按a的KeySeparator输出；
+//a和b可以是不同格式的配置，JSON中的对象和数组展开到叶子节点，两者都区分大小写时key才区分大小写。
+//不是本包创建的配置视为空配置
+func Diff(a, b Configer, opts ...DiffOption) []Change {
+	var o diffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	old, oa := flattenConfig(a)
+	cur, ob := flattenConfig(b)
+	if oa.KeySeparator != ob.KeySeparator {
+		m := make(map[string]flatValue, len(cur))
+		for k, v := range cur {
+			m[strings.ReplaceAll(k, ob.KeySeparator, oa.KeySeparator)] = v
+		}
+		cur = m
+	}
+	fold := func(k string) string {
+		if oa.CaseSensitive && ob.CaseSensitive {
+			return k
+		}
+		return strings.ToLower(k)
+	}
+	oldKeys := make(map[string]string, len(old))
+	for k := range old {
+		oldKeys[fold(k)] = k
+	}
+
+	var changes []Change
+	for k, nv := range cur {
+		key, found := oldKeys[fold(k)]
+		if !found {
+			changes = append(changes, newChange(ChangeAdded, nv.section, k, nil, nv.raw, oa))
+			continue
+		}
+		delete(oldKeys, fold(k))
+		if ov := old[key]; !diffEqual(ov.raw, nv.raw, o.normalize) {
+			changes = append(changes, newChange(ChangeModified, nv.section, k, ov.raw, nv.raw, oa))
+		}
+	}
+	for _, k := range oldKeys {
+		ov := old[k]
+		changes = append(changes, newChange(ChangeRemoved, ov.section, k, ov.raw, nil, oa))
+	}
+
+	isDefault := func(section string) bool {
+		return oa.equal(section, oa.DefaultSection) || ob.equal(section, ob.DefaultSection)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		si, sj := changes[i].Section, changes[j].Section
+		if di, dj := isDefault(si), isDefault(sj); di != dj {
+			return di
+		}
+		if si, sj = strings.ToLower(si), strings.ToLower(sj); si != sj {
+			return si < sj
+		}
+		return strings.ToLower(changes[i].Key) < strings.ToLower(changes[j].Key)
+	})
+	return changes
+}
+
+//name为key去掉section前缀后的部分，用于按section分组输出
+func newChange(t ChangeType, section, key string, old, cur interface{}, o *Options) Change {
+	name := key
+	if prefix := section + o.KeySeparator; len(key) > len(prefix) && o.equal(key[:len(prefix)], prefix) {
+		name = key[len(prefix):]
+	}
+	return Change{Type: t, Section: section, Key: key, Old: old, New: cur, name: name}
+}
+
+//与Watch使用同样的方式展开配置，返回展开的配置项和配置的选项
+func flattenConfig(c Configer) (map[string]flatValue, *Options) {
+	switch c := c.(type) {
+	case *IniConfigContainer:
+		s := c.state.Load()
+		return s.flatten(), s.opts
+	case *JsonCfgContainer:
+		s := c.state.Load()
+		return s.flatten(), s.opts
+	}
+	o := newOptions()
+	return map[string]flatValue{}, &o
+}
+
+func diffEqual(a, b interface{}, normalize bool) bool {
+	if normalize {
+		return normalizeValue(a) == normalizeValue(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+//数字转换为float64，true/false转换为bool，其余转换为字符串
+func normalizeValue(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	s := strings.TrimSpace(ToString(v))
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
+
+//按format("text"或"json")输出差异。text格式按section分组，每行以"-"表示删除的值、"+"表示新增的值，
+//修改的配置项输出为一行"-"加一行"+"
+func WriteDiff(w io.Writer, changes []Change, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		if changes == nil {
+			changes = []Change{}
+		}
+		b, err := json.MarshalIndent(changes, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, LINE_BREAK...))
+		return err
+	case "text":
+		var buf bytes.Buffer
+		section := ""
+		for i, c := range changes {
+			if i == 0 || !strings.EqualFold(c.Section, section) {
+				section = c.Section
+				if i > 0 {
+					buf.WriteString(LINE_BREAK)
+				}
+				fmt.Fprintf(&buf, "[%s]%s", section, LINE_BREAK)
+			}
+			key := c.name
+			if len(key) == 0 {
+				key = c.Key
+			}
+			if c.Type != ChangeAdded {
+				fmt.Fprintf(&buf, "-%s = %s%s", key, diffString(c.Old), LINE_BREAK)
+			}
+			if c.Type != ChangeRemoved {
+				fmt.Fprintf(&buf, "+%s = %s%s", key, diffString(c.New), LINE_BREAK)
+			}
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	return errors.New("unknown diff format " + format + ", should be text or json")
+}
+
+//将差异输出为字符串，便于直接写入日志
+func DiffString(changes []Change, format string) string {
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, changes, format); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+func diffString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return ToString(v)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Error("same config should have no changes:", changes)
+	}
+	b.Set("mysql::port", "3307")
+	b.Set("mysql::timeout", "5s")
+	b.Delete("mysql::passwd")
+	b.Set("num", "6")
+
+	want := []Change{
+		{Type: ChangeModified, Section: "default", Key: "num", Old: "5", New: "6"},
+		{Type: ChangeRemoved, Section: "mysql", Key: "mysql::passwd", Old: "root"},
+		{Type: ChangeModified, Section: "mysql", Key: "mysql::port", Old: "3306", New: "3307"},
+		{Type: ChangeAdded, Section: "mysql", Key: "mysql::timeout", New: "5s"},
+	}
+	changes := Diff(a, b)
+	if len(changes) != len(want) {
+		t.Error("unexpected changes:", changes)
+		return
+	}
+	for i := range want {
+		if c := changes[i]; c.Type != want[i].Type || c.Section != want[i].Section || c.Key != want[i].Key ||
+			c.Old != want[i].Old || c.New != want[i].New {
+			t.Errorf("got %+v, want %+v", changes[i], want[i])
+		}
+	}
+
+	text := DiffString(changes, "text")
+	if text != "[default]\n-num = 5\n+num = 6\n\n[mysql]\n-passwd = root\n-port = 3306\n+port = 3307\n+timeout = 5s\n" {
+		t.Error("unexpected text diff:", text)
+	}
+	var out []map[string]interface{}
+	if err = json.Unmarshal([]byte(DiffString(changes, "json")), &out); err != nil || len(out) != 4 {
+		t.Error("unexpected json diff:", out, err)
+	} else if out[1]["type"] != "removed" || out[1]["key"] != "mysql::passwd" || out[1]["new"] != nil {
+		t.Error("unexpected json change:", out[1])
+	}
+	if DiffString(nil, "json") != "[]\n" || DiffString(nil, "text") != "" {
+		t.Error("empty diff should render as empty")
+	}
+	if err = WriteDiff(nil, changes, "yaml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
+
+func TestDiffFormats(t *testing.T) {
+	ini, err := NewConfigData("ini", []byte("num = 5\nIsOpen = TRUE\n[MySQL]\nport = 3306\nhost = db\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	js, err := NewConfigData("json", []byte(`{"num": 5.0, "isopen": true, "mysql": {"port": 3306, "host": "db", "slaves": ["a"]}}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	changes := Diff(ini, js, WithTypeNormalization())
+	if len(changes) != 1 || changes[0].Type != ChangeAdded || changes[0].Key != "mysql::slaves::0" || changes[0].Section != "mysql" {
+		t.Error("unexpected normalized changes:", changes)
+	}
+
+	var keys []string
+	for _, c := range Diff(ini, js) {
+		keys = append(keys, c.Type.String()+" "+c.Key)
+	}
+	if strings.Join(keys, ",") != "modified isopen,modified num,modified mysql::port,added mysql::slaves::0" {
+		t.Error("unexpected raw changes:", keys)
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	ini, err := NewConfigData("ini", []byte("port = 80\n[mysql]\nhost = db\n"), WithDefaultSection("global"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	js, err := NewConfigData("json", []byte(`{"port": "80", "mysql": {"host": "db"}}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if changes := Diff(ini, js); len(changes) != 0 {
+		t.Error("default section should not matter:", changes)
+	}
+
+	sep, err := NewConfigData("json", []byte(`{"port": "81", "mysql": {"host": "db", "user": "root"}}`), WithKeySeparator("."))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	changes := Diff(ini, sep)
+	if len(changes) != 2 || changes[0].Key != "port" || changes[0].Section != "default" || changes[1].Key != "mysql::user" {
+		t.Error("unexpected changes:", changes)
+	}
+	if text := DiffString(changes, "text"); text != "[default]\n-port = 80\n+port = 81\n\n[mysql]\n+user = root\n" {
+		t.Error("unexpected text diff:", text)
+	}
+
+	//与Watch的事件一致
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ini.Watch(ctx, "**")
+	before := ini.Snapshot()
+	ini.Set("port", "81")
+	e := nextEvent(t, ch)
+	if changes := Diff(before, ini); len(changes) != 1 || changes[0].Key != e.Key || changes[0].Section != e.Section {
+		t.Error("diff and watch disagree:", changes, e)
+	}
+}
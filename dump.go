@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//默认的敏感配置项匹配规则，匹配时忽略大小写，规则语法同path.Match
+var DefaultSensitivePatterns = []string{"*passwd*", "*password*", "*secret*", "*token*"}
+
+//敏感配置项的值在输出时被替换为REDACTED
+const REDACTED = "******"
+
+//Redactor 用于打印配置时屏蔽敏感配置项，比如在启动日志或者debug接口中输出当前配置。
+//配置项的名字(key)或者完整路径(sec::key)匹配任一规则，或者被显式标记为敏感时，其值会被屏蔽
+type Redactor struct {
+	patterns  []string
+	sensitive []string    //MarkSensitive标记的key，打印时按配置的KeySeparator和DefaultSection解析
+	fields    [][2]string //MarkStruct标记的{section, key}，section为空时表示默认section
+}
+
+//返回一个新的Redactor，patterns为空时使用DefaultSensitivePatterns
+func NewRedactor(patterns ...string) *Redactor {
+	if len(patterns) == 0 {
+		patterns = DefaultSensitivePatterns
+	}
+	r := &Redactor{}
+	for _, p := range patterns {
+		r.patterns = append(r.patterns, strings.ToLower(p))
+	}
+	return r
+}
+
+//显式标记敏感配置项，key支持sec::key的方式(分隔符为配置的KeySeparator)，不带section时表示默认section下的配置项
+func (r *Redactor) MarkSensitive(keys ...string) *Redactor {
+	r.sensitive = append(r.sensitive, keys...)
+	return r
+}
+
+//根据结构体的tag标记敏感配置项，tag格式为 config:"name,sensitive"，
+//结构体类型的字段表示一个section，其内部字段为该section下的配置项，比如
+//
+//	type MySQL struct {
+//		Addr   string `config:"addr"`
+//		Passwd string `config:"passwd,sensitive"`
+//	}
+//	type Conf struct {
+//		MySQL MySQL `config:"mysql"`
+//	}
+func (r *Redactor) MarkStruct(v interface{}) *Redactor {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		r.markStruct(t, "")
+	}
+	return r
+}
+
+func (r *Redactor) markStruct(t reflect.Type, section string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, sensitive := parseConfigTag(field)
+		if name == "-" {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && section == "" && ft.PkgPath() != "time" {
+			r.markStruct(ft, name)
+			continue
+		}
+		if sensitive {
+			r.fields = append(r.fields, [2]string{section, name})
+		}
+	}
+}
+
+func parseConfigTag(field reflect.StructField) (name string, sensitive bool) {
+	parts := strings.Split(field.Tag.Get("config"), ",")
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	for _, opt := range parts[1:] {
+		if strings.TrimSpace(opt) == "sensitive" {
+			sensitive = true
+		}
+	}
+	return name, sensitive
+}
+
+//判断配置项是否敏感，key支持sec::key的方式，按默认选项解析
+func (r *Redactor) IsSensitive(key string) bool {
+	o := newOptions()
+	return r.matcher(&o)(key)
+}
+
+//返回按o解析key的匹配函数，key为完整路径，不带section时表示默认section下的配置项
+func (r *Redactor) matcher(o *Options) func(key string) bool {
+	marked := make(map[string]bool, len(r.sensitive)+len(r.fields))
+	for _, key := range r.sensitive {
+		marked[normalizeDumpKey(key, o)] = true
+	}
+	for _, f := range r.fields {
+		if len(f[0]) == 0 {
+			f[0] = o.DefaultSection
+		}
+		marked[normalizeDumpKey(f[0]+o.KeySeparator+f[1], o)] = true
+	}
+	return func(key string) bool {
+		key = normalizeDumpKey(key, o)
+		if marked[key] {
+			return true
+		}
+		name := key
+		if idx := strings.LastIndex(key, o.KeySeparator); idx >= 0 {
+			name = key[idx+len(o.KeySeparator):]
+		}
+		for _, p := range r.patterns {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(p, key); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func normalizeDumpKey(key string, o *Options) string {
+	key = strings.ToLower(key)
+	if !strings.Contains(key, o.KeySeparator) {
+		key = strings.ToLower(o.DefaultSection) + o.KeySeparator + key
+	}
+	return key
+}
+
+//按format("ini"或"json")打印c中的配置，敏感配置项的值被屏蔽。
+//打印的是配置文件中的原始值，不会展开引用或者解密，section和key按字母顺序输出
+func (r *Redactor) Dump(w io.Writer, c Configer, format string) error {
+	data, o, err := r.redact(c)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		out := make(map[string]interface{})
+		for sec, kv := range data {
+			if o.equal(sec, o.DefaultSection) {
+				for k, v := range kv {
+					out[k] = v
+				}
+				continue
+			}
+			out[sec] = kv
+		}
+		b, err := json.MarshalIndent(out, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, LINE_BREAK...))
+		return err
+	case "ini":
+		return dumpIni(w, data, o)
+	}
+	return errors.New("unknown dump format " + format + ", should be ini or json")
+}
+
+//使用默认规则打印c中的配置
+func Dump(w io.Writer, c Configer, format string) error {
+	return NewRedactor().Dump(w, c, format)
+}
+
+//使用默认规则将c中的配置打印为字符串，便于直接写入日志
+func DumpString(c Configer, format string) string {
+	var buf bytes.Buffer
+	if err := Dump(&buf, c, format); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+//将配置数据转换为 section-->key:val 的形式并屏蔽敏感值，不在section下的配置项放在默认section下
+func (r *Redactor) redact(c Configer) (map[string]map[string]interface{}, *Options, error) {
+	o := configOptions(c)
+	sensitive := r.matcher(o)
+	out := make(map[string]map[string]interface{})
+	switch data := c.GetCfgData().(type) {
+	case map[string]map[string]string:
+		for sec, kv := range data {
+			out[sec] = make(map[string]interface{})
+			for k, v := range kv {
+				out[sec][k] = redactValue(sec+o.KeySeparator+k, v, sensitive, o)
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range data {
+			if kv, ok := v.(map[string]interface{}); ok {
+				out[k] = redactValue(k, kv, sensitive, o).(map[string]interface{})
+				continue
+			}
+			if _, ok := out[o.DefaultSection]; !ok {
+				out[o.DefaultSection] = make(map[string]interface{})
+			}
+			out[o.DefaultSection][k] = redactValue(k, v, sensitive, o)
+		}
+	default:
+		return nil, nil, errors.New("unsupported config data type")
+	}
+	return out, o, nil
+}
+
+//递归屏蔽敏感值，返回新的值，不修改原始数据
+func redactValue(key string, val interface{}, sensitive func(string) bool, o *Options) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			m[k] = redactValue(key+o.KeySeparator+k, vv, sensitive, o)
+		}
+		return m
+	case []interface{}:
+		if sensitive(key) {
+			return REDACTED
+		}
+		s := make([]interface{}, len(v))
+		for i, vv := range v {
+			s[i] = redactValue(key, vv, sensitive, o)
+		}
+		return s
+	}
+	if sensitive(key) {
+		return REDACTED
+	}
+	return val
+}
+
+func dumpIni(w io.Writer, data map[string]map[string]interface{}, o *Options) error {
+	var buf bytes.Buffer
+	writeSection := func(kv map[string]interface{}) {
+		keys := make([]string, 0, len(kv))
+		for k := range kv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			val, ok := kv[k].(string)
+			if !ok {
+				b, _ := json.Marshal(kv[k])
+				val = string(b)
+			}
+			buf.WriteString(k + string(EQUAL) + val + LINE_BREAK)
+		}
+	}
+
+	sections := make([]string, 0, len(data))
+	for sec, kv := range data {
+		if o.equal(sec, o.DefaultSection) {
+			writeSection(kv)
+		} else {
+			sections = append(sections, sec)
+		}
+	}
+	sort.Strings(sections)
+	for _, sec := range sections {
+		if buf.Len() > 0 {
+			buf.WriteString(LINE_BREAK)
+		}
+		buf.WriteString(string(SEC_START) + sec + string(SEC_END) + LINE_BREAK)
+		writeSection(data[sec])
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDumpIni(t *testing.T) {
+	config, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	out := DumpString(config, "ini")
+	if strings.Contains(out, "passwd=root") || !strings.Contains(out, "passwd="+REDACTED) {
+		t.Error("passwd not redacted:", out)
+	}
+	if !strings.Contains(out, "[mysql]\naddr=127.0.0.1\n") {
+		t.Error("dump ini failed:", out)
+	}
+	if config.String("mysql::passwd") != "root" {
+		t.Error("dump should not modify config")
+	}
+}
+
+func TestDumpJson(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	if err = NewRedactor("*user*").MarkSensitive("num").Dump(&buf, config, "json"); err != nil {
+		t.Error(err)
+		return
+	}
+	var out map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Error(err)
+		return
+	}
+	mysql := out["mysql"].(map[string]interface{})
+	if mysql["user"] != REDACTED || mysql["passwd"] != "root" || out["num"] != REDACTED {
+		t.Error("custom pattern not honoured:", buf.String())
+	}
+}
+
+func TestRedactorMarkStruct(t *testing.T) {
+	type MySQL struct {
+		Addr string `config:"addr"`
+		Port int    `config:"port,sensitive"`
+	}
+	type Conf struct {
+		Addr  string `config:"addr,sensitive"`
+		MySQL MySQL  `config:"mysql"`
+	}
+	r := NewRedactor().MarkStruct(&Conf{})
+	if !r.IsSensitive("mysql::port") || !r.IsSensitive("addr") || r.IsSensitive("mysql::addr") {
+		t.Error("struct tag not honoured")
+	}
+}
+
+func TestDumpOptions(t *testing.T) {
+	config, err := NewConfigData("json", []byte(`{"x": 1, "port": 80, "mysql": {"user": "root", "addr": "db"}}`),
+		WithDefaultSection("global"), WithKeySeparator("."))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	r := NewRedactor("*user*").MarkSensitive("x", "mysql.addr")
+	if err = r.Dump(&buf, config, "json"); err != nil {
+		t.Error(err)
+		return
+	}
+	var out map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Error(err)
+		return
+	}
+	mysql, _ := out["mysql"].(map[string]interface{})
+	if _, ok := out["global"]; ok || out["x"] != REDACTED || out["port"] != 80.0 || mysql["user"] != REDACTED || mysql["addr"] != REDACTED {
+		t.Error("options not honoured:", buf.String())
+	}
+
+	ini, err := NewConfigData("ini", []byte("x = 1\nport = 80\n[mysql]\naddr = db\n"), WithDefaultSection("global"), WithKeySeparator("."))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	buf.Reset()
+	if err = r.Dump(&buf, ini, "ini"); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != "port=80\nx="+REDACTED+"\n\n[mysql]\naddr="+REDACTED+"\n" {
+		t.Error("ini options not honoured:", buf.String())
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/app.ini":        {Data: []byte("include \"db/mysql.ini\"\n[app]\nname = demo\n")},
+		"conf/db/mysql.ini":   {Data: []byte("[mysql]\naddr = 127.0.0.1\n")},
+		"conf/app.json":       {Data: []byte(`{"name": "demo"}`)},
+		"conf/broken.ini":     {Data: []byte("include \"missing.ini\"\n")},
+		"conf/app.properties": {Data: []byte("name = demo\n")},
+	}
+	config, err := NewConfigFS("ini", fsys, "conf/app.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("mysql::addr"); val != "127.0.0.1" {
+		t.Error("include in fs failed:", val)
+	}
+	if val := config.String("app::name"); val != "demo" {
+		t.Error("Get string failed:", val)
+	}
+	if _, err = NewConfigFS("ini", fsys, "conf/broken.ini"); err == nil {
+		t.Error("missing include should fail")
+	}
+
+	for _, name := range []string{"conf/app.json", "conf/app.properties"} {
+		config, err = LoadFS(fsys, name)
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if val := config.String("name"); val != "demo" {
+			t.Error(name, "Get string failed:", val)
+		}
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	for name, data := range map[string]string{"ini": "port = 8080\n", "json": `{"port": 8080}`, "": `{port: 8080}`} {
+		config, err := NewConfigReader(name, strings.NewReader(data))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if val, err := config.Int("port"); err != nil || val != 8080 {
+			t.Error(name, "Get int failed:", val, err)
+		}
+	}
+}
@@ -5,15 +5,17 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"os"
-	"os/user"
+	"path"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
-	"sync"
+	"time"
 )
 
+//DEFAULT_SECTION、NUM_COMMENT、SEM_COMMENT、EQUAL为Options的默认值，修改后只影响之后创建的配置对象，
+//需要不同的行为时使用WithDefaultSection等选项
 var (
 	DEFAULT_SECTION = "default" //配置文件中的某些不在某个sec下，默认将其放在defaultSection下
 	NUM_COMMENT     = []byte{'#'}
@@ -29,39 +31,68 @@ var (
 type IniConfig struct {
 }
 
+//读取配置时不加锁，修改配置时在快照的副本上修改后整体替换，见container
 type IniConfigContainer struct {
+	container[iniState, *iniState]
+}
+
+//INI配置的快照，发布后不再修改
+type iniState struct {
 	data       map[string]map[string]string //保存配置数据，sec-->key:val
 	secComment map[string]string            //保存注释 sec-->comment
-	keyComment map[string]string            // key --> comment 某个配置的注释
-	sync.RWMutex
+	keyComment map[string]map[string]string // section --> key --> comment 某个配置的注释
+	opts       *Options
+}
+
+func newIniContainer(s *iniState) *IniConfigContainer {
+	c := &IniConfigContainer{}
+	c.init(s)
+	return c
 }
 
-func (ini *IniConfig) Parse(filename string) (Configer, error) {
-	return ini.parseFile(filename)
+//用快照s创建新的INI配置，供container创建只读快照和事务
+func (c *IniConfigContainer) derive(s *iniState) (Configer, *container[iniState, *iniState]) {
+	n := newIniContainer(s)
+	return n, &n.container
 }
 
-func (ini *IniConfig) parseFile(filename string) (*IniConfigContainer, error) {
-	f, err := os.Open(filename)
+func (ini *IniConfig) Parse(filename string, opts ...Option) (Configer, error) {
+	data, file, err := readTrackedFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ini.parseData(nil, filepath.Dir(filename), data, newOptions(opts...))
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	data, err := ioutil.ReadAll(f)
+	c := newIniContainer(s)
+	c.file = file
+	return c, nil
+}
+
+//fsys为nil时从本地文件系统读取，否则从fsys中读取，include的文件也从同一个文件系统中读取
+func (ini *IniConfig) parseFile(fsys fs.FS, filename string, o Options) (*iniState, error) {
+	if fsys == nil {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return ini.parseData(nil, filepath.Dir(filename), data, o)
+	}
+	data, err := fs.ReadFile(fsys, filename)
 	if err != nil {
 		return nil, err
 	}
-	return ini.parseData(filepath.Dir(filename), data)
+	return ini.parseData(fsys, path.Dir(filename), data, o)
 }
 
-func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, error) {
-	cfg := &IniConfigContainer{
+func (ini *IniConfig) parseData(fsys fs.FS, dir string, data []byte, o Options) (*iniState, error) {
+	cfg := &iniState{
 		data:       make(map[string]map[string]string),
 		secComment: make(map[string]string),
-		keyComment: make(map[string]string),
-		RWMutex:    sync.RWMutex{},
+		keyComment: make(map[string]map[string]string),
+		opts:       &o,
 	}
-	cfg.Lock()
-	defer cfg.Unlock()
 
 	var comment bytes.Buffer
 	buf := bufio.NewReader(bytes.NewBuffer(data))
@@ -75,7 +106,9 @@ func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, e
 		}
 	}
 
-	section := DEFAULT_SECTION
+	//严格模式下检查重复的section和key，只检查当前文件，include的文件可以覆盖
+	seen := make(map[[2]string]bool) //{section, key}，key为空时表示section本身
+	section := o.DefaultSection
 	for {
 		line, _, err := buf.ReadLine()
 		if err == io.EOF {
@@ -93,16 +126,18 @@ func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, e
 		}
 
 		//读取注释
-		var bcomment []byte
-
-		switch {
-		case bytes.HasPrefix(line, NUM_COMMENT):
-			bcomment = NUM_COMMENT
-		case bytes.HasPrefix(line, SEM_COMMENT):
-			bcomment = SEM_COMMENT
+		var bcomment string
+		for _, prefix := range o.CommentPrefixes {
+			if bytes.HasPrefix(line, []byte(prefix)) {
+				bcomment = prefix
+				break
+			}
 		}
-		if bcomment != nil {
-			line = bytes.TrimLeft(line, string(bcomment))
+		if len(bcomment) > 0 {
+			//注释符可以是任意字符串，按前缀去掉，连续重复的注释符比如"##"一起去掉
+			for bytes.HasPrefix(line, []byte(bcomment)) {
+				line = line[len(bcomment):]
+			}
 			if comment.Len() > 0 {
 				comment.WriteByte('\n')
 			}
@@ -112,7 +147,14 @@ func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, e
 
 		//读取section
 		if bytes.HasPrefix(line, SEC_START) && bytes.HasSuffix(line, SEC_END) {
-			section = string(bytes.ToLower(line[1 : len(line)-1]))
+			//保留原始大小写，不区分大小写时与已有的同名section合并
+			section = cfg.sectionName(string(line[1 : len(line)-1]))
+			if o.Strict {
+				if seen[[2]string{section, ""}] {
+					return nil, errors.New("duplicate section " + section)
+				}
+				seen[[2]string{section, ""}] = true
+			}
 			if comment.Len() > 0 {
 				cfg.secComment[section] = comment.String()
 				comment.Reset()
@@ -128,40 +170,43 @@ func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, e
 		}
 
 		//解析配置项
-		keyValue := bytes.SplitN(line, EQUAL, 2)
+		keyValue := splitKeyValue(line, o.Delimiters)
 		key := string(bytes.TrimSpace(keyValue[0]))
-		key = strings.ToLower(key)
 
 		//判断文件是否包含其他配置文件，是的话先解析被包含的配置文件 include "other.conf"
-		if len(keyValue) == 1 && strings.HasPrefix(key, "include") {
+		if len(keyValue) == 1 && strings.HasPrefix(strings.ToLower(key), "include") {
 			includefiles := strings.Fields(key)
-			if includefiles[0] == "include" && len(includefiles) == 2 {
+			if strings.ToLower(includefiles[0]) == "include" && len(includefiles) == 2 {
 				otherfile := strings.Trim(includefiles[1], "\"")
-				if !filepath.IsAbs(otherfile) {
+				if fsys != nil {
+					//fs.FS中的路径总是以/分隔并且相对于根目录
+					otherfile = path.Join(dir, otherfile)
+				} else if !filepath.IsAbs(otherfile) {
 					otherfile = filepath.Join(dir, otherfile)
 				}
 
-				i, err := ini.parseFile(otherfile)
+				i, err := ini.parseFile(fsys, otherfile, o)
 				if err != nil {
 					return nil, err
 				}
 
+				//被包含文件中的section和key按当前的大小写选项与已有的合并
 				for sec, dt := range i.data {
-					if _, ok := cfg.data[sec]; !ok {
-						cfg.data[sec] = make(map[string]string)
+					name := cfg.sectionName(sec)
+					if _, ok := cfg.data[name]; !ok {
+						cfg.data[name] = make(map[string]string)
+					}
+					if comm, ok := i.secComment[sec]; ok {
+						cfg.secComment[name] = comm
 					}
 					for k, v := range dt {
-						cfg.data[sec][k] = v
+						kname := cfg.keyName(name, k)
+						cfg.data[name][kname] = v
+						if comm, ok := i.keyComment[sec][k]; ok {
+							cfg.setKeyComment(name, kname, comm)
+						}
 					}
 				}
-
-				for sec, comm := range i.secComment {
-					cfg.secComment[sec] = comm
-				}
-
-				for k, comm := range i.keyComment {
-					cfg.keyComment[k] = comm
-				}
 				continue
 			}
 		}
@@ -169,34 +214,72 @@ func (ini *IniConfig) parseData(dir string, data []byte) (*IniConfigContainer, e
 		if len(keyValue) != 2 {
 			return nil, errors.New("read content error," + string(line) + " format should be key = value")
 		}
+		key = cfg.keyName(section, key)
+		if o.Strict {
+			if seen[[2]string{section, key}] {
+				return nil, errors.New("duplicate key " + key + " in section " + section)
+			}
+			seen[[2]string{section, key}] = true
+		}
 		val := bytes.TrimSpace(keyValue[1])
 		if bytes.HasPrefix(val, QUOTE) {
 			val = bytes.Trim(val, `"`)
 		}
 		cfg.data[section][key] = string(val)
 		if comment.Len() > 0 {
-			cfg.keyComment[section+"."+key] = comment.String()
+			cfg.setKeyComment(section, key, comment.String())
 			comment.Reset()
 		}
 	}
 	return cfg, nil
 }
 
-func (ini *IniConfig) ParseData(data []byte) (Configer, error) {
-	dir := "tmp"
-	currentUser, err := user.Current()
-	if err == nil {
-		dir = "tmp-" + currentUser.Username
+//按delims中最先出现的分隔符将line拆分为key和value，没有分隔符时返回整行
+func splitKeyValue(line []byte, delims []string) [][]byte {
+	idx, n := -1, 0
+	for _, delim := range delims {
+		if i := bytes.Index(line, []byte(delim)); i >= 0 && (idx < 0 || i < idx) {
+			idx, n = i, len(delim)
+		}
 	}
-	dir = filepath.Join(os.TempDir(), dir)
-	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+	if idx < 0 {
+		return [][]byte{line}
+	}
+	return [][]byte{line[:idx], line[idx+n:]}
+}
+
+//include的相对路径相对于当前工作目录
+func (ini *IniConfig) ParseData(data []byte, opts ...Option) (Configer, error) {
+	s, err := ini.parseData(nil, ".", data, newOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	return newIniContainer(s), nil
+}
+
+func (ini *IniConfig) ParseReader(r io.Reader, opts ...Option) (Configer, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ini.ParseData(data, opts...)
+}
+
+//include的相对路径相对于name所在的目录，并且在fsys中查找
+func (ini *IniConfig) ParseFS(fsys fs.FS, name string, opts ...Option) (Configer, error) {
+	s, err := ini.parseFile(fsys, name, newOptions(opts...))
+	if err != nil {
 		return nil, err
 	}
-	return ini.parseData(dir, data)
+	return newIniContainer(s), nil
 }
 
 func (c *IniConfigContainer) Bool(key string) (bool, error) {
-	return ParseBool(c.getdata(key))
+	v, err := c.value(key)
+	if err != nil {
+		return false, err
+	}
+	return ParseBool(v)
 }
 
 func (c *IniConfigContainer) DefaultBool(key string, defaultVal bool) bool {
@@ -216,11 +299,19 @@ func (c *IniConfigContainer) DefaultInt(key string, defaultVal int) int {
 }
 
 func (c *IniConfigContainer) Int(key string) (int, error) {
-	return strconv.Atoi(c.getdata(key))
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(v)
 }
 
 func (c *IniConfigContainer) Int64(key string) (int64, error) {
-	return strconv.ParseInt(c.getdata(key), 10, 64)
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt64(v)
 }
 
 func (c *IniConfigContainer) DefaultInt64(key string, defaultval int64) int64 {
@@ -232,7 +323,11 @@ func (c *IniConfigContainer) DefaultInt64(key string, defaultval int64) int64 {
 }
 
 func (c *IniConfigContainer) Float(key string) (float64, error) {
-	return strconv.ParseFloat(c.getdata(key), 64)
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat64(v)
 }
 func (c *IniConfigContainer) DefaultFloat(key string, defaultval float64) float64 {
 	v, err := c.Float(key)
@@ -242,10 +337,63 @@ func (c *IniConfigContainer) DefaultFloat(key string, defaultval float64) float6
 	return v
 }
 
+func (c *IniConfigContainer) Duration(key string) (time.Duration, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return ParseDuration(v)
+}
+
+func (c *IniConfigContainer) DefaultDuration(key string, defaultval time.Duration) time.Duration {
+	v, err := c.Duration(key)
+	if err != nil {
+		return defaultval
+	}
+	return v
+}
+
+func (c *IniConfigContainer) Time(key string, layouts ...string) (time.Time, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseTime(v, layouts...)
+}
+
+func (c *IniConfigContainer) Bytes(key string) (int64, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return ParseBytes(v)
+}
+
+func (c *IniConfigContainer) DefaultBytes(key string, defaultval int64) int64 {
+	v, err := c.Bytes(key)
+	if err != nil {
+		return defaultval
+	}
+	return v
+}
+
 func (c *IniConfigContainer) String(key string) string {
 	return c.getdata(key)
 }
 
+func (c *IniConfigContainer) GetString(key string) (string, error) {
+	return c.value(key)
+}
+
+//返回key对应的原始值，不展开其中的${...}引用
+func (c *IniConfigContainer) RawString(key string) string {
+	if len(key) == 0 {
+		return ""
+	}
+	v, _ := c.state.Load().lookup(key)
+	return v
+}
+
 func (c *IniConfigContainer) DefaultString(key string, defaultval string) string {
 	v := c.String(key)
 	if v == "" {
@@ -271,19 +419,30 @@ func (c *IniConfigContainer) DefaultStrings(key string, defaultval []string) []s
 	return v
 }
 
+//返回section下全部配置的副本，修改返回值不影响配置
 func (c *IniConfigContainer) GetSection(section string) (map[string]string, error) {
-	if v, ok := c.data[section]; ok {
-		return v, nil
+	s := c.state.Load()
+	if _, ok := s.data[s.sectionName(section)]; ok {
+		return s.evalSection(s.sectionName(section))
 	}
 	return nil, errors.New("section not exist")
 }
 
-func (c *IniConfigContainer) SaveConfigFile(filename string) (err error) {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+//在文件锁中原子地保存到文件，见writeFileAtomic；保存到Parse的文件并且该文件已被其他进程修改时返回*ConflictError
+func (c *IniConfigContainer) SaveConfigFile(filename string) error {
+	return c.file.save(filename, c.state.Load().opts.Backups, c)
+}
+
+//将配置按INI格式写入w
+func (c *IniConfigContainer) WriteTo(w io.Writer) (int64, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := c.state.Load().writeTo(buf); err != nil {
+		return 0, err
 	}
-	defer f.Close()
+	return buf.WriteTo(w)
+}
+
+func (s *iniState) writeTo(buf *bytes.Buffer) (err error) {
 
 	getCommentStr := func(section, key string) string {
 		var (
@@ -291,30 +450,31 @@ func (c *IniConfigContainer) SaveConfigFile(filename string) (err error) {
 			ok      bool
 		)
 		if len(key) == 0 {
-			comment, ok = c.secComment[section]
+			comment, ok = s.secComment[section]
 		} else {
-			comment, ok = c.keyComment[key]
+			comment, ok = s.keyComment[section][key]
 		}
 
 		if ok {
+			//增加注释头，默认为"#"
+			prefix := s.opts.CommentPrefixes[0]
 			if len(comment) == 0 || len(strings.TrimSpace(comment)) == 0 {
-				return string(NUM_COMMENT)
+				return prefix
 			}
 
-			//增加注释头"#"
-			prefix := string(NUM_COMMENT)
 			return prefix + strings.Replace(comment, LINE_BREAK, LINE_BREAK+prefix, -1)
 		}
 		return ""
 	}
 
-	buf := bytes.NewBuffer(nil)
 	//先保存defaultsection下的默认全局配置
-	if dt, ok := c.data[DEFAULT_SECTION]; ok {
-		for key, val := range dt {
+	def := s.sectionName(s.opts.DefaultSection)
+	if dt, ok := s.data[def]; ok {
+		for _, key := range sortedKeys(dt) {
+			val := dt[key]
 			if key != " " {
 				//写入配置项注释
-				if v := getCommentStr(DEFAULT_SECTION, key); len(v) > 0 {
+				if v := getCommentStr(def, key); len(v) > 0 {
 					if _, err = buf.WriteString(v + LINE_BREAK); err != nil {
 						return err
 					}
@@ -322,22 +482,26 @@ func (c *IniConfigContainer) SaveConfigFile(filename string) (err error) {
 			}
 
 			//写入配置项
-			if _, err = buf.WriteString(key + string(EQUAL) + val + LINE_BREAK); err != nil {
+			if _, err = buf.WriteString(key + s.opts.Delimiters[0] + val + LINE_BREAK); err != nil {
 				return err
 			}
 		}
 	}
 
 	//保存section下的配置
-	for section, dt := range c.data {
-		if section != DEFAULT_SECTION {
+	for _, section := range s.sections() {
+		if dt := s.data[section]; section != def {
 			if v := getCommentStr(section, ""); len(v) > 0 {
 				if _, err = buf.WriteString(v + LINE_BREAK); err != nil {
 					return err
 				}
 			}
+			if _, err = buf.WriteString(string(SEC_START) + section + string(SEC_END) + LINE_BREAK); err != nil {
+				return err
+			}
 
-			for key, val := range dt {
+			for _, key := range sortedKeys(dt) {
+				val := dt[key]
 				if key != " " {
 					if v := getCommentStr(section, key); len(v) > 0 {
 						if _, err = buf.WriteString(v + LINE_BREAK); err != nil {
@@ -346,7 +510,7 @@ func (c *IniConfigContainer) SaveConfigFile(filename string) (err error) {
 					}
 				}
 
-				if _, err = buf.WriteString(key + string(EQUAL) + val + LINE_BREAK); err != nil {
+				if _, err = buf.WriteString(key + s.opts.Delimiters[0] + val + LINE_BREAK); err != nil {
 					return err
 				}
 			}
@@ -355,71 +519,361 @@ func (c *IniConfigContainer) SaveConfigFile(filename string) (err error) {
 			}
 		}
 	}
-	_, err = buf.WriteTo(f)
-	return err
+	return nil
 }
 
 func (c *IniConfigContainer) Set(key, value string) error {
-	c.Lock()
-	defer c.Unlock()
 	if len(key) == 0 {
 		return errors.New("Key can not be empty")
 	}
+	return c.update(func(s *iniState) error {
+		section, k, err := s.splitSectionKey(key)
+		if err != nil {
+			return err
+		}
+		s.section(section)[k] = value
+		return nil
+	})
+}
 
-	var (
-		section, k string
-		sectionKey = strings.Split(strings.ToLower(key), "::")
-	)
-
-	if len(sectionKey) == 2 {
-		section = sectionKey[0]
-		k = sectionKey[1]
-	} else {
-		section = DEFAULT_SECTION
-		k = sectionKey[0]
+//INI只能保存字符串，val按ToString转换，字符串切片用";"连接，与Strings对应
+func (c *IniConfigContainer) SetValue(key string, val interface{}) error {
+	switch v := val.(type) {
+	case []string:
+		return c.Set(key, strings.Join(v, ";"))
+	case []interface{}:
+		strs, _ := toStrings(v)
+		return c.Set(key, strings.Join(strs, ";"))
+	case map[string]interface{}, map[string]string:
+		return errors.New("ini value can not be an object")
 	}
-	if _, ok := c.data[section]; !ok {
-		c.data[section] = make(map[string]string)
-	}
-	c.data[section][k] = value
-	return nil
+	return c.Set(key, ToString(val))
 }
 
+//key支持sec::key的方式，值中的${...}等引用会被展开；key不是配置项而是section时返回该section下的全部配置
 func (c *IniConfigContainer) GetInerfaceVal(key string) (interface{}, error) {
-	if v, ok := c.data[strings.ToLower(key)]; ok {
-		return v, nil
+	s := c.state.Load()
+	if v, ok := s.lookup(key); ok {
+		return evalValue(key, v, s.lookup, s.opts)
+	}
+	if _, ok := s.data[s.sectionName(key)]; ok {
+		return s.evalSection(s.sectionName(key))
 	}
 	return nil, errors.New("key not exist")
 }
 
+//返回section下全部配置展开引用、解密后的值
+func (s *iniState) evalSection(section string) (map[string]string, error) {
+	out := make(map[string]string, len(s.data[section]))
+	for k, v := range s.data[section] {
+		key := section + s.opts.KeySeparator + k
+		if section == s.sectionName(s.opts.DefaultSection) {
+			key = k
+		}
+		val, err := evalValue(key, v, s.lookup, s.opts)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = val
+	}
+	return out, nil
+}
+
+func copySection(m map[string]string) map[string]string {
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+//返回key对应的值，值中的${...}等引用会被展开，展开失败时返回""
 func (c *IniConfigContainer) getdata(key string) string {
-	if len(key) == 0 {
+	v, err := c.value(key)
+	if err != nil {
 		return ""
 	}
-	c.Lock()
-	defer c.Unlock()
-	key = strings.ToLower(key)
-	var (
-		section, k string
-		sectionKey = strings.Split(key, "::")
-	)
-	if len(sectionKey) == 2 {
-		section = sectionKey[0]
-		k = sectionKey[1]
-	} else {
-		section = DEFAULT_SECTION
-		k = sectionKey[0]
-	}
-	if v, ok := c.data[section]; ok {
+	return v
+}
+
+func (c *IniConfigContainer) value(key string) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	s := c.state.Load()
+	v, _ := s.lookup(key)
+	return evalValue(key, v, s.lookup, s.opts)
+}
+
+func (c *IniConfigContainer) Reload(validators ...Validator) error {
+	_, err := c.reload(validators)
+	return err
+}
+
+//按Parse时的选项重新解析文件，见container.reload
+func (c *IniConfigContainer) reload(validators []Validator) ([]Event, error) {
+	return c.container.reload(validators, func(filename string, data []byte, o Options) (*iniState, error) {
+		return (&IniConfig{}).parseData(nil, filepath.Dir(filename), data, o)
+	}, c.derive)
+}
+
+func (s *iniState) options() *Options {
+	return s.opts
+}
+
+//复制快照，section下的配置只复制引用，修改前通过section复制
+func (s *iniState) clone() *iniState {
+	ns := &iniState{
+		data:       make(map[string]map[string]string, len(s.data)),
+		secComment: make(map[string]string, len(s.secComment)),
+		keyComment: make(map[string]map[string]string, len(s.keyComment)),
+		opts:       s.opts,
+	}
+	for k, v := range s.data {
+		ns.data[k] = v
+	}
+	for k, v := range s.secComment {
+		ns.secComment[k] = v
+	}
+	for sec, m := range s.keyComment {
+		nm := make(map[string]string, len(m))
+		for k, v := range m {
+			nm[k] = v
+		}
+		ns.keyComment[sec] = nm
+	}
+	return ns
+}
+
+//设置配置项的注释，comment为空时删除
+func (s *iniState) setKeyComment(section, key, comment string) {
+	if len(comment) == 0 {
+		delete(s.keyComment[section], key)
+		return
+	}
+	if _, ok := s.keyComment[section]; !ok {
+		s.keyComment[section] = make(map[string]string)
+	}
+	s.keyComment[section][key] = comment
+}
+
+//返回可以修改的section，section与旧快照共享时先复制，不存在时创建
+func (s *iniState) section(name string) map[string]string {
+	m := make(map[string]string, len(s.data[name])+1)
+	for k, v := range s.data[name] {
+		m[k] = v
+	}
+	s.data[name] = m
+	return m
+}
+
+//查找key对应的原始值
+func (s *iniState) lookup(key string) (string, bool) {
+	section, k, err := s.splitSectionKey(key)
+	if err != nil {
+		return "", false
+	}
+	if v, ok := s.data[section]; ok {
 		if vv, ok := v[k]; ok {
-			return vv
+			return vv, true
 		}
 	}
-	return ""
+	return "", false
+}
+
+//返回配置项的注释，多行注释用换行分隔，每行去掉了注释符和首尾空白
+func (c *IniConfigContainer) Comment(key string) string {
+	s := c.state.Load()
+	section, k, err := s.splitSectionKey(key)
+	if err != nil {
+		return ""
+	}
+	return trimComment(s.keyComment[section][k])
+}
+
+//设置配置项的注释，comment为空时删除注释，保存文件时每行注释前加"#"
+func (c *IniConfigContainer) SetComment(key, comment string) error {
+	return c.update(func(s *iniState) error {
+		section, k, err := s.splitSectionKey(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.data[section][k]; !ok {
+			return errors.New("key not exist")
+		}
+		s.setKeyComment(section, k, formatComment(comment))
+		return nil
+	})
 }
+
+//返回section的注释
+func (c *IniConfigContainer) SectionComment(section string) string {
+	s := c.state.Load()
+	return trimComment(s.secComment[s.sectionName(section)])
+}
+
+//设置section的注释，comment为空时删除注释
+func (c *IniConfigContainer) SetSectionComment(section, comment string) error {
+	return c.update(func(s *iniState) error {
+		section := s.sectionName(section)
+		if _, ok := s.data[section]; !ok {
+			return errors.New("section not exist")
+		}
+		if len(comment) == 0 {
+			delete(s.secComment, section)
+		} else {
+			s.secComment[section] = formatComment(comment)
+		}
+		return nil
+	})
+}
+
+//去掉每行注释的首尾空白，解析时保存的注释保留了注释符后面的空格
+func trimComment(comment string) string {
+	lines := strings.Split(comment, LINE_BREAK)
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, LINE_BREAK)
+}
+
+//每行注释前加一个空格，保存后为"# comment"的格式，与解析时保存的格式一致
+func formatComment(comment string) string {
+	lines := strings.Split(strings.TrimRight(comment, LINE_BREAK), LINE_BREAK)
+	for i, line := range lines {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines[i] = " " + line
+		} else {
+			lines[i] = line
+		}
+	}
+	return strings.Join(lines, LINE_BREAK)
+}
+
+//将sec::key拆分为section和key，不带section时section为默认section，分隔符由KeySeparator指定。
+//返回已有的section和key的实际名字，不存在时返回查询时的名字，超过两段时返回错误
+func (s *iniState) splitSectionKey(key string) (section, k string, err error) {
+	sectionKey := strings.Split(key, s.opts.KeySeparator)
+	switch len(sectionKey) {
+	case 1:
+		section, k = s.sectionName(s.opts.DefaultSection), sectionKey[0]
+	case 2:
+		section, k = s.sectionName(sectionKey[0]), sectionKey[1]
+	default:
+		//INI只有section和key两层，不支持JSON那样的嵌套路径
+		return "", "", errors.New("invalid ini key " + key + ", should be key or section" + s.opts.KeySeparator + "key")
+	}
+	return section, s.keyName(section, k), nil
+}
+
+//返回与name匹配的已有section的名字，不存在时返回name
+func (s *iniState) sectionName(name string) string {
+	return matchKey(s.opts, s.data, name)
+}
+
+//返回section下与key匹配的已有配置项的名字，不存在时返回key
+func (s *iniState) keyName(section, key string) string {
+	return matchKey(s.opts, s.data[section], key)
+}
+
+//删除配置项及其注释，key支持sec::key的方式
+func (c *IniConfigContainer) Delete(key string) error {
+	return c.update(func(s *iniState) error {
+		section, k, err := s.splitSectionKey(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.data[section][k]; !ok {
+			return errors.New("key not exist")
+		}
+		delete(s.section(section), k)
+		s.setKeyComment(section, k, "")
+		return nil
+	})
+}
+
+//删除section及其下的全部配置项和注释
+func (c *IniConfigContainer) DeleteSection(section string) error {
+	return c.update(func(s *iniState) error {
+		section := s.sectionName(section)
+		if _, ok := s.data[section]; !ok {
+			return errors.New("section not exist")
+		}
+		delete(s.data, section)
+		delete(s.secComment, section)
+		delete(s.keyComment, section)
+		return nil
+	})
+}
+
+//返回section下全部配置项的名字，按字母排序，section为空时表示DEFAULT_SECTION
+func (c *IniConfigContainer) Keys(section string) []string {
+	s := c.state.Load()
+	if len(section) == 0 {
+		section = s.opts.DefaultSection
+	}
+	return sortedKeys(s.data[s.sectionName(section)])
+}
+
+//返回全部section的名字，DEFAULT_SECTION在最前，其余按字母排序
+func (c *IniConfigContainer) Sections() []string {
+	return c.state.Load().sections()
+}
+
+func (c *IniConfigContainer) HasSection(section string) bool {
+	s := c.state.Load()
+	_, ok := s.data[s.sectionName(section)]
+	return ok
+}
+
+func (s *iniState) sections() []string {
+	var secs []string
+	def := s.sectionName(s.opts.DefaultSection)
+	for sec := range s.data {
+		if sec != def {
+			secs = append(secs, sec)
+		}
+	}
+	sort.Strings(secs)
+	if _, ok := s.data[def]; ok {
+		secs = append([]string{def}, secs...)
+	}
+	return secs
+}
+
+func sortedKeys(m map[string]string) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *IniConfigContainer) Snapshot() Configer {
+	return c.snapshot(c, c.derive)
+}
+
+func (c *IniConfigContainer) View(fn func(Configer) error) error {
+	return fn(c.Snapshot())
+}
+
+func (c *IniConfigContainer) Begin() Tx {
+	return c.begin(c.derive)
+}
+
+//返回全部配置数据的副本，类型为map[string]map[string]string
 func (c *IniConfigContainer) GetCfgData() interface{} {
-	return c.data
+	data := c.state.Load().data
+	cp := make(map[string]map[string]string, len(data))
+	for sec, m := range data {
+		cp[sec] = copySection(m)
+	}
+	return cp
 }
 func init() {
-	Register("ini", &IniConfig{})
+	Register("ini", &IniConfig{}, ".ini", ".conf", ".cfg")
 }
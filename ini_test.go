@@ -2,6 +2,8 @@ package config
 
 import (
 	//"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -95,3 +97,140 @@ func TestSection(t *testing.T) {
 		t.Error("Get int data failed.")
 	}
 }
+
+func TestIniKeysAndSections(t *testing.T) {
+	config, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if secs := config.Sections(); strings.Join(secs, ",") != "default,mysql" {
+		t.Error("Get sections failed:", secs)
+	}
+	if keys := config.Keys("mysql"); strings.Join(keys, ",") != "addr,dbname,passwd,port,user" {
+		t.Error("Get keys failed:", keys)
+	}
+	if keys := config.Keys(""); strings.Join(keys, ",") != "IsOpen,addr,addrs,float,num" {
+		t.Error("Get default keys failed:", keys)
+	}
+	if !config.HasSection("mysql") || config.HasSection("redis") {
+		t.Error("HasSection failed.")
+	}
+
+	if err = config.Delete("mysql::passwd"); err != nil {
+		t.Error(err)
+	}
+	if err = config.Delete("mysql::passwd"); err == nil {
+		t.Error("delete missing key should fail")
+	}
+	if val := config.String("mysql::passwd"); val != "" {
+		t.Error("key not deleted:", val)
+	}
+	if err = config.DeleteSection("mysql"); err != nil {
+		t.Error(err)
+	}
+	if config.HasSection("mysql") || len(config.Keys("mysql")) != 0 {
+		t.Error("section not deleted")
+	}
+}
+
+var commentIni = `
+# listen port
+port = 8080
+# mysql settings
+; used by the api server
+[mysql]
+# database address
+addr = 127.0.0.1
+`
+
+func TestIniComment(t *testing.T) {
+	config, err := NewConfigData("ini", []byte(commentIni))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.Comment("port"); val != "listen port" {
+		t.Error("Get key comment failed:", val)
+	}
+	if val := config.SectionComment("mysql"); val != "mysql settings\nused by the api server" {
+		t.Error("Get section comment failed:", val)
+	}
+	if err = config.SetComment("mysql::addr", "primary address\nreplicas are in mysql::slaves"); err != nil {
+		t.Error(err)
+	}
+	if err = config.SetComment("mysql::missing", "x"); err == nil {
+		t.Error("set comment on missing key should fail")
+	}
+	if err = config.SetSectionComment("mysql", ""); err != nil {
+		t.Error(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "comment.ini")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err = NewConfig("ini", filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.Comment("port"); val != "listen port" {
+		t.Error("key comment not saved:", val)
+	}
+	if val := config.Comment("mysql::addr"); val != "primary address\nreplicas are in mysql::slaves" {
+		t.Error("edited comment not saved:", val)
+	}
+	if val := config.SectionComment("mysql"); val != "" {
+		t.Error("section comment not removed:", val)
+	}
+
+	config.Delete("port")
+	if val := config.Comment("port"); val != "" {
+		t.Error("comment not deleted with key:", val)
+	}
+}
+
+func TestIniDeleteSectionComments(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("[a]\n# drop me\nb.c = 1\n[a.b]\n# keep me\nc = 2\n"), WithStrict())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.DeleteSection("a"); err != nil {
+		t.Error(err)
+	}
+	if val := config.Comment("a.b::c"); val != "keep me" {
+		t.Error("comment of other section deleted:", val)
+	}
+	if val := config.Comment("a::b.c"); val != "" {
+		t.Error("comment not deleted with section:", val)
+	}
+}
+
+func TestIniNestedKey(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("mysql = keep\n[mysql]\nport = 3306\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("mysql::slaves::0", "x"); err == nil {
+		t.Error("nested key should fail")
+	}
+	if err = config.Set("a::b::c", "x"); err == nil {
+		t.Error("nested key should fail")
+	}
+	if err = config.Delete("mysql::port::x"); err == nil {
+		t.Error("nested key should fail")
+	}
+	if err = config.SetComment("mysql::port::x", "c"); err == nil {
+		t.Error("nested key should fail")
+	}
+	if val := config.String("mysql"); val != "keep" {
+		t.Error("top-level key overwritten:", val)
+	}
+	if val := config.String("mysql::port::x"); val != "" {
+		t.Error("nested key should not be found:", val)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//配置值中支持的引用格式：
+//
+//	${sec::key}        引用配置文件中的其他配置项，不带section时引用default下的配置项
+//	${ENV:NAME}        引用环境变量
+//	${name:-default}   引用不存在或者值为空时使用默认值，默认值中也可以包含引用
+//	$${                转义，输出字面量 ${
+//
+//不带ENV:前缀的引用先查找配置项，找不到时再查找同名环境变量，都找不到时展开为空字符串
+const (
+	INTERP_START   = "${"
+	INTERP_ESCAPE  = "$${"
+	INTERP_END     = '}'
+	INTERP_ENV     = "ENV:"
+	INTERP_DEFAULT = ":-"
+
+	maxInterpDepth = 32 //引用的最大嵌套层数，防止通过别名(a与default::a)绕过环检测
+)
+
+var (
+	ErrInterpolationCycle = errors.New("interpolation cycle detected")
+	ErrInterpolationDepth = errors.New("interpolation depth exceeded")
+)
+
+//lookupFunc 返回配置项未经插值的原始值
+type lookupFunc func(key string) (string, bool)
+
+//展开val中的引用，key为val所属的配置项，用于检测循环引用，key是否相同按o判断
+func interpolate(key, val string, lookup lookupFunc, o *Options) (string, error) {
+	return expand(val, lookup, o, []string{key})
+}
+
+func expand(val string, lookup lookupFunc, o *Options, stack []string) (string, error) {
+	if !strings.Contains(val, INTERP_START) {
+		return val, nil
+	}
+	if len(stack) > maxInterpDepth {
+		return "", ErrInterpolationDepth
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(val); {
+		if strings.HasPrefix(val[i:], INTERP_ESCAPE) {
+			buf.WriteString(INTERP_START)
+			i += len(INTERP_ESCAPE)
+			continue
+		}
+		if !strings.HasPrefix(val[i:], INTERP_START) {
+			buf.WriteByte(val[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(val, i+len(INTERP_START))
+		if end < 0 {
+			return "", errors.New("unterminated reference in " + val)
+		}
+		v, err := resolveRef(val[i+len(INTERP_START):end], lookup, o, stack)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(v)
+		i = end + 1
+	}
+	return buf.String(), nil
+}
+
+//返回与start之前的${匹配的}的位置，默认值中可以嵌套${...}
+func closingBrace(val string, start int) int {
+	depth := 0
+	for i := start; i < len(val); i++ {
+		switch {
+		case strings.HasPrefix(val[i:], INTERP_START):
+			depth++
+			i++
+		case val[i] == INTERP_END:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func resolveRef(expr string, lookup lookupFunc, o *Options, stack []string) (string, error) {
+	name, def, hasDef := expr, "", false
+	if idx := strings.Index(expr, INTERP_DEFAULT); idx >= 0 {
+		name, def, hasDef = expr[:idx], expr[idx+len(INTERP_DEFAULT):], true
+	}
+	name = strings.TrimSpace(name)
+
+	var (
+		val string
+		ok  bool
+	)
+	//${env::key}是对env这个section的引用，不是环境变量
+	if len(name) > len(INTERP_ENV) && strings.EqualFold(name[:len(INTERP_ENV)], INTERP_ENV) && name[len(INTERP_ENV)] != ':' {
+		val, ok = os.LookupEnv(name[len(INTERP_ENV):])
+	} else if val, ok = lookup(name); ok {
+		for _, k := range stack {
+			if o.equal(k, name) {
+				return "", fmt.Errorf("%w: %s", ErrInterpolationCycle, strings.Join(append(stack, name), " -> "))
+			}
+		}
+		var err error
+		if val, err = expand(val, lookup, o, append(stack, name)); err != nil {
+			return "", err
+		}
+		if val, err = finishValue(val); err != nil {
+			return "", err
+		}
+	} else {
+		val, ok = os.LookupEnv(name)
+	}
+
+	if (!ok || len(val) == 0) && hasDef {
+		return expand(def, lookup, o, stack)
+	}
+	return val, nil
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+var interpIni = `
+home = "/opt/app"
+logdir = "${home}/logs"
+port = "${INTERP_TEST_PORT:-8080}"
+user = "${ENV:INTERP_TEST_USER}"
+literal = "$${home}"
+a = "${b}"
+b = "${a}"
+[mysql]
+addr = "127.0.0.1"
+dsn = "${user}@${mysql::addr}:${mysql::port:-3306}"
+`
+
+var interpJson = `{
+	"home": "/opt/app",
+	"logdir": "${home}/logs",
+	"port": "${INTERP_TEST_PORT:-8080}",
+	"user": "${ENV:INTERP_TEST_USER}",
+	"literal": "$${home}",
+	"a": "${b}",
+	"b": "${a}",
+	"mysql": {
+		"addr": "127.0.0.1",
+		"dsn": "${user}@${mysql::addr}:${mysql::port:-3306}"
+	}
+}`
+
+func TestInterpolation(t *testing.T) {
+	os.Setenv("INTERP_TEST_USER", "root")
+	defer os.Unsetenv("INTERP_TEST_USER")
+
+	for name, data := range map[string]string{"ini": interpIni, "json": interpJson} {
+		config, err := NewConfigData(name, []byte(data))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if val := config.String("logdir"); val != "/opt/app/logs" {
+			t.Error(name, "reference failed:", val)
+		}
+		if val, err := config.Int("port"); err != nil || val != 8080 {
+			t.Error(name, "default value failed:", val, err)
+		}
+		if val := config.String("mysql::dsn"); val != "root@127.0.0.1:3306" {
+			t.Error(name, "section reference failed:", val)
+		}
+		if val := config.String("literal"); val != "${home}" {
+			t.Error(name, "escape failed:", val)
+		}
+		if val := config.RawString("logdir"); val != "${home}/logs" {
+			t.Error(name, "raw string failed:", val)
+		}
+		if val := config.String("a"); val != "" {
+			t.Error(name, "cycle should expand to empty string:", val)
+		}
+		if _, err := config.Int("a"); !errors.Is(err, ErrInterpolationCycle) {
+			t.Error(name, "cycle not detected:", err)
+		}
+	}
+}
+
+func TestInterpolationEnvFallback(t *testing.T) {
+	os.Setenv("INTERP_TEST_PORT", "9090")
+	defer os.Unsetenv("INTERP_TEST_PORT")
+
+	config, err := NewConfigData("ini", []byte(interpIni))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("port"); err != nil || val != 9090 {
+		t.Error("env fallback failed:", val, err)
+	}
+}
+
+func TestInterpolationCaseSensitive(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("A = ${a}\na = x\nb = ${B}\nB = ${b}\n"), WithCaseSensitive())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.GetString("A"); err != nil || val != "x" {
+		t.Error("case sensitive reference failed:", val, err)
+	}
+	if _, err := config.GetString("b"); !errors.Is(err, ErrInterpolationCycle) {
+		t.Error("cycle not detected:", err)
+	}
+}
+
+func TestInterpolationEnvSection(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("addr = ${env::host}:${ENV::port:-80}\n[env]\nhost = db\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("addr"); val != "db:80" {
+		t.Error("env section reference failed:", val)
+	}
+}
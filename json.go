@@ -1,87 +1,318 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	//"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
-	"os"
 	//"reflect"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
 type JsonConfig struct {
 }
 
-func (jc *JsonConfig) Parse(filename string) (Configer, error) {
-	return jc.parseFile(filename)
-}
-func (jc *JsonConfig) parseData(data []byte) (*JsonCfgContainer, error) {
-	cfg := &JsonCfgContainer{
-		data: make(map[string]interface{}),
+func (jc *JsonConfig) Parse(filename string, opts ...Option) (Configer, error) {
+	data, file, err := readTrackedFile(filename)
+	if err != nil {
+		return nil, err
 	}
-	err := json.Unmarshal(data, &cfg.data)
+	cfg, err := jc.parseData(data, newOptions(opts...))
 	if err != nil {
 		return nil, err
 	}
-	return cfg, err
-
+	cfg.file = file
+	return cfg, nil
 }
 
-func (jc *JsonConfig) ParseData(data []byte) (Configer, error) {
-	cfg := &JsonCfgContainer{
-		data: make(map[string]interface{}),
+//数字解析为json.Number而不是float64，保证大整数不丢失精度，与INI中的字符串表示一致
+func (jc *JsonConfig) parseData(data []byte, o Options) (*JsonCfgContainer, error) {
+	cfg := &jsonState{
+		data:    make(map[string]interface{}),
+		comment: make(map[string]string),
+		opts:    &o,
+	}
+	if o.Strict {
+		if err := checkDuplicateKeys(data); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&cfg.data); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
 	}
-	err := json.Unmarshal(data, &cfg.data)
+	return newJsonContainer(cfg, false), nil
+}
+
+func (jc *JsonConfig) ParseData(data []byte, opts ...Option) (Configer, error) {
+	cfg, err := jc.parseData(data, newOptions(opts...))
 	if err != nil {
 		return nil, err
 	}
-	return cfg, err
+	return cfg, nil
+}
 
+func (jc *JsonConfig) ParseReader(r io.Reader, opts ...Option) (Configer, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return jc.ParseData(data, opts...)
 }
 
-func (jc *JsonConfig) parseFile(filename string) (*JsonCfgContainer, error) {
-	data, err := ioutil.ReadFile(filename)
+func (jc *JsonConfig) ParseFS(fsys fs.FS, name string, opts ...Option) (Configer, error) {
+	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
-	return jc.parseData(data)
+	return jc.ParseData(data, opts...)
+}
+
+//encoding/json遇到重复的key时保留最后一个，严格模式下逐个读取token检查同一对象中重复的key
+func checkDuplicateKeys(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	type object struct {
+		keys   map[string]bool
+		expect bool //下一个token是否为key
+	}
+	var stack []*object
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var top *object
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if top != nil && top.expect {
+			if key, ok := tok.(string); ok {
+				if top.keys[key] {
+					return errors.New("duplicate key " + key)
+				}
+				top.keys[key] = true
+				top.expect = false
+				continue
+			}
+		}
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &object{keys: make(map[string]bool), expect: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, nil)
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		//一个值读取完毕，所在对象的下一个token为key
+		if len(stack) > 0 && stack[len(stack)-1] != nil {
+			stack[len(stack)-1].expect = true
+		}
+	}
 }
 
+//与IniConfigContainer相同，读取时直接读取当前的快照，修改时复制快照修改后整体替换，见container
 type JsonCfgContainer struct {
-	data map[string]interface{}
-	sync.RWMutex
+	container[jsonState, *jsonState]
+	jsonc bool //由jsonc/json5适配器解析，保存文件时按JSONC格式写入注释
 }
 
-//给配置文件的某个字段设置值，支持sec::key的方式选择key值
+//JSON配置的快照，发布后不再修改
+type jsonState struct {
+	data    map[string]interface{}
+	comment map[string]string //保存注释 sec::key-->comment，标准JSON不支持注释，保存文件时会丢失
+	opts    *Options
+}
+
+func newJsonContainer(s *jsonState, jsonc bool) *JsonCfgContainer {
+	c := &JsonCfgContainer{jsonc: jsonc}
+	c.init(s)
+	return c
+}
+
+//用快照s创建新的JSON配置，格式与c相同，供container创建只读快照和事务
+func (c *JsonCfgContainer) derive(s *jsonState) (Configer, *container[jsonState, *jsonState]) {
+	n := newJsonContainer(s, c.jsonc)
+	return n, &n.container
+}
+
+func (c *JsonCfgContainer) Reload(validators ...Validator) error {
+	_, err := c.reload(validators)
+	return err
+}
+
+//按Parse时的格式和选项重新解析文件，见container.reload
+func (c *JsonCfgContainer) reload(validators []Validator) ([]Event, error) {
+	return c.container.reload(validators, func(_ string, data []byte, o Options) (*jsonState, error) {
+		var (
+			cfg *JsonCfgContainer
+			err error
+		)
+		if c.jsonc {
+			cfg, err = (&JsoncConfig{}).parseData(data, o)
+		} else {
+			cfg, err = (&JsonConfig{}).parseData(data, o)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cfg.state.Load(), nil
+	}, c.derive)
+}
+
+func (s *jsonState) options() *Options {
+	return s.opts
+}
+
+//深度复制快照，setPath等会直接修改对象和数组
+func (s *jsonState) clone() *jsonState {
+	ns := &jsonState{
+		data:    copyJson(s.data).(map[string]interface{}),
+		comment: make(map[string]string, len(s.comment)),
+		opts:    s.opts,
+	}
+	for k, v := range s.comment {
+		ns.comment[k] = v
+	}
+	return ns
+}
+
+//深度复制JSON对象和数组，其他值不可变，直接返回
+func copyJson(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			m[k] = copyJson(vv)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, vv := range v {
+			arr[i] = copyJson(vv)
+		}
+		return arr
+	}
+	return val
+}
+
+//给配置文件的某个字段设置值，支持sec::key的方式选择key值，值保存为字符串
 func (c *JsonCfgContainer) Set(key, val string) error {
-	c.Lock()
-	defer c.Unlock()
-	sectionKey := strings.Split(key, "::")
-	if len(sectionKey) == 2 {
-		var section, k string
-		section = sectionKey[0]
-		k = sectionKey[1]
-		_, ok := c.data[section]
-		if !ok {
-			c.data[section] = make(map[string]interface{})
+	return c.SetValue(key, val)
+}
+
+//给配置文件的某个字段设置任意类型的值，key支持多级路径，比如 mysql::slaves::0::addr，
+//路径中不存在的对象会被创建，下一级为数字时创建数组，数组长度不够时用null填充。
+//val先经过json编码再解码，因此结构体、map等会被转换为JSON对象，数字保存为json.Number
+func (c *JsonCfgContainer) SetValue(key string, val interface{}) error {
+	if len(key) == 0 {
+		return errors.New("Key can not be empty")
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return err
+	}
+
+	return c.update(func(s *jsonState) error {
+		_, err := s.setPath(s.data, strings.Split(key, s.opts.KeySeparator), v)
+		return err
+	})
+}
+
+//SetValue时数组下标最多超过末尾的个数，超过时返回错误，避免错误的下标分配大量内存
+const maxArrayGap = 1024
+
+//在node中按path设置值，返回设置后的node，数组扩容后地址会变化，因此需要由上一级重新保存
+func (s *jsonState) setPath(node interface{}, path []string, val interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	k := path[0]
+	switch n := node.(type) {
+	case nil:
+		if _, err := strconv.Atoi(k); err == nil {
+			return s.setPath([]interface{}{}, path, val)
 		}
-		if vv, ok := c.data[section].(map[string]interface{}); ok {
-			vv[k] = val
+		return s.setPath(make(map[string]interface{}), path, val)
+	case map[string]interface{}:
+		k = s.jsonKey(n, k)
+		child, err := s.setPath(n[k], path[1:], val)
+		if err != nil {
+			return nil, err
 		}
+		n[k] = child
+		return n, nil
+	case []interface{}:
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 {
+			return nil, errors.New("invalid array index " + k)
+		}
+		//下标超过末尾时中间补null，但不为远超末尾的下标分配空间
+		if i > len(n)+maxArrayGap {
+			return nil, errors.New("array index " + k + " out of range, array has " + strconv.Itoa(len(n)) + " elements")
+		}
+		for len(n) <= i {
+			n = append(n, nil)
+		}
+		child, err := s.setPath(n[i], path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
 	}
-	c.data[key] = val
-	return nil
+	return nil, errors.New("can not set " + strings.Join(path, "::") + " on a non-object value")
+}
+
+//返回m中与k匹配的key，见matchKey
+func (s *jsonState) jsonKey(m map[string]interface{}, k string) string {
+	return matchKey(s.opts, m, k)
 }
 
 //返回指定key的Val值得string格式，key支持sec::key的方式
 func (c *JsonCfgContainer) String(key string) string {
-	c.Lock()
-	defer c.Unlock()
-	val := c.getdata(key)
-	if val != nil {
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err == nil && val != nil {
+		return ToString(val)
+	}
+	return ""
+}
+
+//与String相同，但插值或者file://等引用解析失败时返回错误
+func (c *JsonCfgContainer) GetString(key string) (string, error) {
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil || val == nil {
+		return "", err
+	}
+	return ToString(val), nil
+}
+
+//返回key对应的原始值，不展开其中的${...}引用
+func (c *JsonCfgContainer) RawString(key string) string {
+	s := c.state.Load()
+	if val := s.getdata(key); val != nil {
 		return ToString(val)
 	}
 	return ""
@@ -89,72 +320,82 @@ func (c *JsonCfgContainer) String(key string) string {
 
 //返回指定key值得Val的切片
 func (c *JsonCfgContainer) Strings(key string) []string {
-	c.Lock()
-	defer c.Unlock()
-	var resp []string
-	val := c.getdata(key)
-	switch vv := val.(type) {
-	case string:
-		resp = append(resp, vv)
-	case []string:
-		resp = vv
-	case []interface{}:
-		for _, vvv := range vv {
-			resp = append(resp, vvv.(string))
-		}
-	default:
-		resp = nil
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return nil
+	}
+	resp, err := toStrings(val)
+	if err != nil {
+		return nil
 	}
 	return resp
 }
 
 //返回指定key对应val的int值
 func (c *JsonCfgContainer) Int(key string) (int, error) {
-	c.Lock()
-	defer c.Unlock()
-	val := c.getdata(key)
-	switch vv := val.(type) {
-	case string:
-		return strconv.Atoi(vv)
-	case int:
-		return vv, nil
-	case float64:
-		return int(vv), nil
-	case float32:
-		return int(vv), nil
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return 0, err
 	}
-	return 0, errors.New("val is not valid")
+	return toInt(val)
 }
 
 //返回指定key对应val得int64值
 func (c *JsonCfgContainer) Int64(key string) (int64, error) {
-	c.Lock()
-	defer c.Unlock()
-	val := c.getdata(key)
-	if v, ok := val.(string); ok {
-		return strconv.ParseInt(v, 10, 64)
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return 0, err
 	}
-	return 0, errors.New("val is not valid")
+	return toInt64(val)
 }
 func (c *JsonCfgContainer) Bool(key string) (bool, error) {
-	c.Lock()
-	defer c.Unlock()
-	return ParseBool(c.getdata(key))
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return false, err
+	}
+	return ParseBool(val)
 }
 func (c *JsonCfgContainer) Float(key string) (float64, error) {
-	c.Lock()
-	defer c.Unlock()
-	val := c.getdata(key)
-
-	switch vv := val.(type) {
-	case float64:
-		return vv, nil
-	case float32:
-		return float64(vv), nil
-	case string:
-		return strconv.ParseFloat(vv, 64)
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat64(val)
+}
+
+//返回指定key的时长，支持"1h30m"以及表示秒数的数字
+func (c *JsonCfgContainer) Duration(key string) (time.Duration, error) {
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return ParseDuration(val)
+}
+
+//按layouts依次解析时间，默认使用time.RFC3339
+func (c *JsonCfgContainer) Time(key string, layouts ...string) (time.Time, error) {
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseTime(val, layouts...)
+}
+
+//返回指定key表示的字节数，支持"512MB"、"1GiB"等格式
+func (c *JsonCfgContainer) Bytes(key string) (int64, error) {
+	s := c.state.Load()
+	val, err := s.value(key)
+	if err != nil {
+		return 0, err
 	}
-	return 0, errors.New("val is not valid")
+	return ParseBytes(val)
 }
 
 //返回指定key的val的值，若key对应的val为空，给该key对应的val设置我defaultVal
@@ -186,6 +427,20 @@ func (c *JsonCfgContainer) DefaultInt64(key string, defaultVal int64) int64 {
 	}
 	return v
 }
+func (c *JsonCfgContainer) DefaultDuration(key string, defaultVal time.Duration) time.Duration {
+	v, err := c.Duration(key)
+	if err != nil {
+		return defaultVal
+	}
+	return v
+}
+func (c *JsonCfgContainer) DefaultBytes(key string, defaultVal int64) int64 {
+	v, err := c.Bytes(key)
+	if err != nil {
+		return defaultVal
+	}
+	return v
+}
 func (c *JsonCfgContainer) DefaultBool(key string, defaultVal bool) bool {
 	v, err := c.Bool(key)
 	if err != nil {
@@ -202,20 +457,33 @@ func (c *JsonCfgContainer) DefaultFloat(key string, defaultVal float64) float64
 }
 
 //返回给定key的val，并将val转型为interface{}类型
+//key支持sec::key的方式，字符串值中的${...}等引用会被展开，对象和数组返回副本
 func (c *JsonCfgContainer) GetInerfaceVal(key string) (interface{}, error) {
-	if val, ok := c.data[strings.ToLower(key)]; ok {
-		return val, nil
+	s := c.state.Load()
+	if s.getdata(key) != nil {
+		val, err := s.value(key)
+		if err != nil {
+			return nil, err
+		}
+		return copyJson(val), nil
+	}
+	if val, ok := s.data[s.jsonKey(s.data, key)]; ok {
+		return copyJson(val), nil
 	}
 	return nil, errors.New("get interface data failed.")
 }
 
 //返回某个section下的全部配置
 func (c *JsonCfgContainer) GetSection(section string) (map[string]string, error) {
-	c.Lock()
-	defer c.Unlock()
+	s := c.state.Load()
 	var secmap = make(map[string]string)
-	if v, ok := c.data[strings.ToLower(section)]; ok {
-		for k, val := range v.(map[string]interface{}) {
+	name := s.jsonKey(s.data, section)
+	if v, ok := s.data[name].(map[string]interface{}); ok {
+		for k, val := range v {
+			val, err := s.eval(name+s.opts.KeySeparator+k, val)
+			if err != nil {
+				return nil, err
+			}
 			secmap[k] = ToString(val)
 		}
 		return secmap, nil
@@ -225,50 +493,319 @@ func (c *JsonCfgContainer) GetSection(section string) (map[string]string, error)
 }
 
 //将配置信息保存到文件
+//与IniConfigContainer.SaveConfigFile相同
 func (c *JsonCfgContainer) SaveConfigFile(filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+	return c.file.save(filename, c.state.Load().opts.Backups, c)
+}
+
+//将配置写入w，jsonc/json5适配器解析的配置按JSONC格式写入注释
+func (c *JsonCfgContainer) WriteTo(w io.Writer) (int64, error) {
+	var (
+		s    = c.state.Load()
+		data []byte
+		err  error
+	)
+	if c.jsonc {
+		data, err = marshalJsonc(s.data, s.comment)
+	} else {
+		data, err = json.Marshal(s.data)
 	}
-	defer f.Close()
-	data, err := json.Marshal(c.data)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = f.Write(data)
-	if err != nil {
-		return err
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+//返回key对应的值，其中全部字符串(包括对象和数组中的)的${...}等引用会被展开
+func (s *jsonState) value(key string) (interface{}, error) {
+	return s.eval(key, s.getdata(key))
+}
+
+//展开val中全部字符串的引用，对象和数组返回新的副本，key为val的路径
+func (s *jsonState) eval(key string, val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		return evalValue(key, v, s.lookup, s.opts)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, vv := range v {
+			var err error
+			if out[i], err = s.eval(key+s.opts.KeySeparator+strconv.Itoa(i), vv); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			var err error
+			if out[k], err = s.eval(key+s.opts.KeySeparator+k, vv); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return val, nil
+}
+
+//查找key对应的原始值并转换为字符串
+func (s *jsonState) lookup(key string) (string, bool) {
+	val := s.getdata(key)
+	if val == nil {
+		return "", false
+	}
+	return ToString(val), true
+}
+
+//按sec::key或者多级路径查找原始值，数组元素用下标表示，比如 addrs::0
+func (s *jsonState) getdata(key string) interface{} {
+	val, _, _ := s.walk(key)
+	return val
+}
+
+//按路径查找配置项，返回其值以及文档中实际的路径(key的大小写可能与查询时不同)
+func (s *jsonState) walk(key string) (interface{}, string, bool) {
+	return s.walkPath(strings.Split(key, s.opts.KeySeparator))
+}
+
+//与walk相同，路径已经拆分，返回的实际路径总是用"::"连接，作为注释的key
+func (s *jsonState) walkPath(keys []string) (interface{}, string, bool) {
+	var (
+		cur  interface{} = s.data
+		path []string
+	)
+	for _, k := range keys {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			k = s.jsonKey(node, k)
+			v, ok := node[k]
+			if !ok {
+				return nil, "", false
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, "", false
+			}
+			cur = node[i]
+		default:
+			return nil, "", false
+		}
+		path = append(path, k)
+	}
+	return cur, strings.Join(path, "::"), true
+}
+
+//返回配置项的注释，key支持多级路径
+func (c *JsonCfgContainer) Comment(key string) string {
+	s := c.state.Load()
+	if _, path, ok := s.walk(key); ok {
+		return s.comment[path]
+	}
+	return ""
+}
+
+//设置配置项的注释，comment为空时删除注释
+func (c *JsonCfgContainer) SetComment(key, comment string) error {
+	return c.update(func(s *jsonState) error {
+		return s.setComment(key, comment)
+	})
+}
+
+func (s *jsonState) setComment(key, comment string) error {
+	_, path, ok := s.walk(key)
+	if !ok {
+		return errors.New("key not exist")
+	}
+	if comment = strings.TrimSpace(comment); len(comment) == 0 {
+		delete(s.comment, path)
+	} else {
+		s.comment[path] = comment
 	}
 	return nil
 }
 
-func (c *JsonCfgContainer) getdata(key string) interface{} {
-	sectionKey := strings.Split(strings.ToLower(key), "::")
-	if len(sectionKey) >= 2 {
-		value, ok := c.data[sectionKey[0]]
+//返回section的注释，即值为对象的顶层配置项的注释
+func (c *JsonCfgContainer) SectionComment(section string) string {
+	s := c.state.Load()
+	if !s.hasSection(section) {
+		return ""
+	}
+	if _, path, ok := s.walk(section); ok {
+		return s.comment[path]
+	}
+	return ""
+}
+
+func (c *JsonCfgContainer) SetSectionComment(section, comment string) error {
+	return c.update(func(s *jsonState) error {
+		if !s.hasSection(section) {
+			return errors.New("section not exist")
+		}
+		return s.setComment(section, comment)
+	})
+}
+
+//删除数组arrPath中下标为i的元素后，将后面元素的注释前移
+func (s *jsonState) shiftComment(arrPath string, i int) {
+	prefix := arrPath + "::"
+	shifted := make(map[string]string)
+	for k, comm := range s.comment {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := strings.SplitN(k[len(prefix):], "::", 2)
+		j, err := strconv.Atoi(rest[0])
+		if err != nil || j <= i {
+			continue
+		}
+		rest[0] = strconv.Itoa(j - 1)
+		shifted[prefix+strings.Join(rest, "::")] = comm
+		delete(s.comment, k)
+	}
+	for k, comm := range shifted {
+		s.comment[k] = comm
+	}
+}
+
+//删除path及其下级配置项的注释
+func (s *jsonState) deleteComment(path string) {
+	for k := range s.comment {
+		if k == path || strings.HasPrefix(k, path+"::") {
+			delete(s.comment, k)
+		}
+	}
+}
+
+//删除配置项及其注释，key支持多级路径，删除数组元素时后面的元素前移
+func (c *JsonCfgContainer) Delete(key string) error {
+	return c.update(func(s *jsonState) error {
+		_, actual, ok := s.walk(key)
 		if !ok {
-			return nil
+			return errors.New("key not exist")
+		}
+		path := strings.Split(actual, "::")
+		parentPath := strings.Join(path[:len(path)-1], "::")
+		parent := interface{}(s.data)
+		if len(path) > 1 {
+			parent, _, _ = s.walkPath(path[:len(path)-1])
+		}
+		s.deleteComment(actual)
+
+		k := path[len(path)-1]
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			delete(node, k)
+		case []interface{}:
+			i, _ := strconv.Atoi(k)
+			s.shiftComment(parentPath, i)
+			_, err := s.setPath(s.data, path[:len(path)-1], append(node[:i:i], node[i+1:]...))
+			return err
+		}
+		return nil
+	})
+}
+
+//删除section，即值为对象的顶层配置项；section为DEFAULT_SECTION时删除值不是对象的顶层配置项
+func (c *JsonCfgContainer) DeleteSection(section string) error {
+	return c.update(func(s *jsonState) error {
+		if !s.hasSection(section) {
+			return errors.New("section not exist")
 		}
-		if v, ok := value.(map[string]interface{}); ok {
-			if value, ok = v[sectionKey[1]]; !ok {
-				return nil
+		//与INI相同，默认section是值不是对象的顶层配置项
+		if s.opts.equal(section, s.opts.DefaultSection) {
+			for k, v := range s.data {
+				if _, ok := v.(map[string]interface{}); !ok {
+					delete(s.data, k)
+					s.deleteComment(k)
+				}
 			}
-			return value
-		} else {
-			return nil
 		}
+		k := s.jsonKey(s.data, section)
+		if _, ok := s.data[k].(map[string]interface{}); ok {
+			delete(s.data, k)
+			s.deleteComment(k)
+		}
+		return nil
+	})
+}
 
+//返回section下全部配置项的名字，按字母排序；section为空或者DEFAULT_SECTION时
+//返回值不是对象的顶层配置项，section支持多级路径
+func (c *JsonCfgContainer) Keys(section string) []string {
+	s := c.state.Load()
+	var keys []string
+	if len(section) == 0 || s.opts.equal(section, s.opts.DefaultSection) {
+		for k, v := range s.data {
+			if _, ok := v.(map[string]interface{}); !ok {
+				keys = append(keys, k)
+			}
+		}
+	} else if m, ok := s.getdata(section).(map[string]interface{}); ok {
+		for k := range m {
+			keys = append(keys, k)
+		}
 	}
+	sort.Strings(keys)
+	return keys
+}
+
+//返回全部section的名字，即值为对象的顶层配置项，按字母排序；
+//存在值不是对象的顶层配置项时DEFAULT_SECTION排在最前
+func (c *JsonCfgContainer) Sections() []string {
+	return c.state.Load().sections()
+}
 
-	if val, ok := c.data[key]; ok {
-		return val
+func (s *jsonState) sections() []string {
+	var (
+		secs       []string
+		hasDefault bool
+	)
+	for k, v := range s.data {
+		if _, ok := v.(map[string]interface{}); ok {
+			secs = append(secs, k)
+		} else {
+			hasDefault = true
+		}
 	}
-	return nil
+	sort.Strings(secs)
+	if hasDefault {
+		secs = append([]string{s.opts.DefaultSection}, secs...)
+	}
+	return secs
+}
+
+func (c *JsonCfgContainer) HasSection(section string) bool {
+	return c.state.Load().hasSection(section)
+}
+
+func (s *jsonState) hasSection(section string) bool {
+	for _, sec := range s.sections() {
+		if s.opts.equal(sec, section) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *JsonCfgContainer) Snapshot() Configer {
+	return c.snapshot(c, c.derive)
+}
+
+func (c *JsonCfgContainer) View(fn func(Configer) error) error {
+	return fn(c.Snapshot())
+}
+
+func (c *JsonCfgContainer) Begin() Tx {
+	return c.begin(c.derive)
 }
 
+//返回全部配置数据的副本，类型为map[string]interface{}
 func (c *JsonCfgContainer) GetCfgData() interface{} {
-	return c.data
+	return copyJson(c.state.Load().data)
 }
 func init() {
-	Register("json", &JsonConfig{})
+	Register("json", &JsonConfig{}, ".json")
 }
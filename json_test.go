@@ -2,6 +2,9 @@ package config
 
 import (
 	//"os"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -137,3 +140,184 @@ func TestSectionJsonSet(t *testing.T) {
 		t.Error("Get failed,")
 	}
 }
+
+func TestJsonSetValue(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("mysql::port", 3307); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("mysql::slaves::1::addr", "192.168.1.2"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("cache", struct {
+		Size    int      `json:"size"`
+		Servers []string `json:"servers"`
+	}{512, []string{"a", "b"}}); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("num::sub", 1); err == nil {
+		t.Error("set below a scalar should fail")
+	}
+
+	data := config.GetCfgData().(map[string]interface{})
+	if _, ok := data["mysql::port"]; ok {
+		t.Error("stray path key in document")
+	}
+	if val, err := config.Int("mysql::port"); err != nil || val != 3307 {
+		t.Error("Get typed value failed:", val, err)
+	}
+	if val := config.String("mysql::slaves::1::addr"); val != "192.168.1.2" {
+		t.Error("Get nested value failed:", val)
+	}
+	if val, err := config.GetInerfaceVal("mysql::slaves::0"); err == nil {
+		t.Error("array slot should be null:", val)
+	}
+	if val := config.Strings("cache::servers"); len(val) != 2 || val[1] != "b" {
+		t.Error("Get structured value failed:", val)
+	}
+
+	filename := filepath.Join(t.TempDir(), "my.json")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	saved, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(string(saved), `"port":3307`) {
+		t.Error("typed value not saved as number:", string(saved))
+	}
+}
+
+func TestSectionJsonSetNoStrayKey(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	config.Set("mysql::tablename", "familyinfo")
+	if _, ok := config.GetCfgData().(map[string]interface{})["mysql::tablename"]; ok {
+		t.Error("Set should not write the path as a top-level key")
+	}
+}
+
+func TestJsonKeysAndSections(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if secs := config.Sections(); strings.Join(secs, ",") != "default,mysql" {
+		t.Error("Get sections failed:", secs)
+	}
+	if keys := config.Keys("mysql"); strings.Join(keys, ",") != "addr,dbname,passwd,port,user" {
+		t.Error("Get keys failed:", keys)
+	}
+	if keys := config.Keys(""); strings.Join(keys, ",") != "IsOpen,addr,addrs,float,num" {
+		t.Error("Get default keys failed:", keys)
+	}
+	if !config.HasSection("mysql") || config.HasSection("addr") {
+		t.Error("HasSection failed.")
+	}
+
+	if err = config.Delete("addrs::0"); err != nil {
+		t.Error(err)
+	}
+	if val := config.Strings("addrs"); len(val) != 1 || val[0] != "192.168.1.1" {
+		t.Error("array element not deleted:", val)
+	}
+	if err = config.Delete("mysql::passwd"); err != nil {
+		t.Error(err)
+	}
+	if err = config.Delete("mysql::passwd"); err == nil {
+		t.Error("delete missing key should fail")
+	}
+	if err = config.DeleteSection("addr"); err == nil {
+		t.Error("delete non-object section should fail")
+	}
+	if err = config.DeleteSection("mysql"); err != nil || config.HasSection("mysql") {
+		t.Error("section not deleted", err)
+	}
+}
+
+func TestJsonComment(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetSectionComment("mysql", "database settings"); err != nil {
+		t.Error(err)
+	}
+	if err = config.SetComment("MySQL::Port", "default 3306"); err != nil {
+		t.Error(err)
+	}
+	if err = config.SetComment("addrs::1", "backup"); err != nil {
+		t.Error(err)
+	}
+	if val := config.SectionComment("mysql"); val != "database settings" {
+		t.Error("Get section comment failed:", val)
+	}
+	if val := config.Comment("mysql::port"); val != "default 3306" {
+		t.Error("Get key comment failed:", val)
+	}
+
+	config.Delete("addrs::0")
+	if val := config.Comment("addrs::0"); val != "backup" {
+		t.Error("comment not shifted with array element:", val)
+	}
+	config.DeleteSection("mysql")
+	config.SetValue("mysql::port", 3306)
+	if val := config.Comment("mysql::port"); val != "" {
+		t.Error("comment not deleted with section:", val)
+	}
+}
+
+func TestJsonSetValueIndex(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SetValue("addrs::99999999999", "x"); err == nil {
+		t.Error("huge index should fail")
+	}
+	if err = config.SetValue("addrs::2", "10.0.0.1"); err != nil {
+		t.Error(err)
+	}
+	if val := config.Strings("addrs"); len(val) != 3 || val[2] != "10.0.0.1" {
+		t.Error("append failed:", val)
+	}
+}
+
+func TestJsonDeleteDefaultSection(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !config.HasSection("default") || len(config.Keys("default")) == 0 {
+		t.Error("default section should exist")
+	}
+	if err = config.DeleteSection("DEFAULT"); err != nil {
+		t.Error(err)
+	}
+	if config.HasSection("default") || len(config.Keys("")) != 0 || config.String("addr") != "" {
+		t.Error("default section not deleted:", config.Keys(""))
+	}
+	if val := config.String("mysql::addr"); val != "127.0.0.1" {
+		t.Error("other sections should be kept:", val)
+	}
+	if err = config.DeleteSection("default"); err == nil {
+		t.Error("deleting missing default section should fail")
+	}
+}
@@ -0,0 +1,537 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//JsoncConfig 解析带注释的JSON(JSONC)以及JSON5的常用扩展：
+//
+//	// 单行注释和 /* */ 多行注释
+//	数组和对象末尾多余的逗号
+//	不带引号的key，比如 {port: 3306}
+//	单引号字符串，比如 'root'
+//	十六进制数字、带+号以及小数点开头或结尾的数字
+//
+//配置项前面的注释以及同一行末尾的注释会关联到该配置项，通过Comment读取，SaveConfigFile时写回
+type JsoncConfig struct {
+}
+
+func (jc *JsoncConfig) Parse(filename string, opts ...Option) (Configer, error) {
+	data, file, err := readTrackedFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := jc.parseData(data, newOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	cfg.file = file
+	return cfg, nil
+}
+
+func (jc *JsoncConfig) ParseData(data []byte, opts ...Option) (Configer, error) {
+	cfg, err := jc.parseData(data, newOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (jc *JsoncConfig) ParseReader(r io.Reader, opts ...Option) (Configer, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return jc.ParseData(data, opts...)
+}
+
+func (jc *JsoncConfig) ParseFS(fsys fs.FS, name string, opts ...Option) (Configer, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return jc.ParseData(data, opts...)
+}
+
+func (jc *JsoncConfig) parseData(data []byte, o Options) (*JsonCfgContainer, error) {
+	cfg := &jsonState{
+		data:    make(map[string]interface{}),
+		comment: make(map[string]string),
+		opts:    &o,
+	}
+	p := &jsoncParser{data: bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), comment: cfg.comment, strict: o.Strict}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.data) || p.data[p.pos] != '{' {
+		return nil, p.errorf("top-level value should be an object")
+	}
+	v, err := p.parseValue("")
+	if err != nil {
+		return nil, err
+	}
+	if err = p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf("invalid data after top-level value")
+	}
+	cfg.data = v.(map[string]interface{})
+	return newJsonContainer(cfg, true), nil
+}
+
+type jsoncParser struct {
+	data    []byte
+	pos     int
+	pending []string          //尚未关联到配置项的注释
+	comment map[string]string //path-->comment
+	strict  bool              //重复的key视为错误
+}
+
+func (p *jsoncParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.data[:p.pos], []byte{'\n'}) + 1
+	return fmt.Errorf("jsonc line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+//跳过空白和注释，注释保存到pending中
+func (p *jsoncParser) skip() error {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		case c == '/' && p.pos+1 < len(p.data) && (p.data[p.pos+1] == '/' || p.data[p.pos+1] == '*'):
+			comment, err := p.readComment()
+			if err != nil {
+				return err
+			}
+			p.pending = append(p.pending, comment)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *jsoncParser) readComment() (string, error) {
+	if p.data[p.pos+1] == '/' {
+		end := bytes.IndexByte(p.data[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.data) - p.pos
+		}
+		comment := string(p.data[p.pos+2 : p.pos+end])
+		p.pos += end
+		return strings.TrimSpace(comment), nil
+	}
+	end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+	if end < 0 {
+		return "", p.errorf("unterminated comment")
+	}
+	lines := strings.Split(string(p.data[p.pos+2:p.pos+2+end]), "\n")
+	p.pos += end + 4
+	var out []string
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if len(line) > 0 {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, LINE_BREAK), nil
+}
+
+//将pending中的注释关联到path
+func (p *jsoncParser) attach(path string) {
+	if len(p.pending) == 0 {
+		return
+	}
+	p.addComment(path, strings.Join(p.pending, LINE_BREAK))
+	p.pending = nil
+}
+
+func (p *jsoncParser) addComment(path, comment string) {
+	if len(comment) == 0 {
+		return
+	}
+	if old, ok := p.comment[path]; ok {
+		comment = old + LINE_BREAK + comment
+	}
+	p.comment[path] = comment
+}
+
+//读取与上一个值在同一行的注释，关联到path
+func (p *jsoncParser) trailingComment(path string) error {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos+1 < len(p.data) && p.data[p.pos] == '/' && (p.data[p.pos+1] == '/' || p.data[p.pos+1] == '*') {
+		comment, err := p.readComment()
+		if err != nil {
+			return err
+		}
+		p.addComment(path, comment)
+	}
+	return nil
+}
+
+func joinPath(parent, key string) string {
+	if len(parent) == 0 {
+		return key
+	}
+	return parent + "::" + key
+}
+
+func (p *jsoncParser) parseValue(path string) (interface{}, error) {
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of data")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.parseObject(path)
+	case c == '[':
+		return p.parseArray(path)
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	}
+	word := p.readIdent()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, p.errorf("invalid value %q", word)
+}
+
+func (p *jsoncParser) parseObject(path string) (interface{}, error) {
+	p.pos++
+	obj := make(map[string]interface{})
+	//与{或[在同一行的注释属于这个值本身，而不是第一个成员
+	if len(path) > 0 {
+		if err := p.trailingComment(path); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			p.pending = nil
+			return obj, nil
+		}
+
+		var key string
+		if c := p.data[p.pos]; c == '"' || c == '\'' {
+			var err error
+			if key, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		} else if key = p.readIdent(); len(key) == 0 {
+			return nil, p.errorf("invalid object key")
+		}
+		keyPath := joinPath(path, key)
+		p.attach(keyPath)
+
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("missing ':' after key %q", key)
+		}
+		p.pos++
+		val, err := p.parseValue(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj[key]; ok && p.strict {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		obj[key] = val
+		if err = p.endMember(keyPath, '}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *jsoncParser) parseArray(path string) (interface{}, error) {
+	p.pos++
+	arr := make([]interface{}, 0)
+	//与{或[在同一行的注释属于这个值本身，而不是第一个成员
+	if len(path) > 0 {
+		if err := p.trailingComment(path); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			p.pending = nil
+			return arr, nil
+		}
+		elemPath := joinPath(path, strconv.Itoa(len(arr)))
+		p.attach(elemPath)
+		val, err := p.parseValue(elemPath)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, val)
+		if err = p.endMember(elemPath, ']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//处理成员后面的逗号以及同一行的注释，允许最后一个成员后面有逗号
+func (p *jsoncParser) endMember(path string, closing byte) error {
+	if err := p.trailingComment(path); err != nil {
+		return err
+	}
+	if err := p.skip(); err != nil {
+		return err
+	}
+	if p.pos >= len(p.data) {
+		return p.errorf("unexpected end of data")
+	}
+	switch p.data[p.pos] {
+	case ',':
+		p.pos++
+		return p.trailingComment(path)
+	case closing:
+		return nil
+	}
+	return p.errorf("expected ',' or '%c'", closing)
+}
+
+func (p *jsoncParser) readIdent() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '_' || c == '$' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *jsoncParser) parseString() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+	var buf strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return buf.String(), nil
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c != '\\':
+			buf.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.pos >= len(p.data) {
+			break
+		}
+		esc := p.data[p.pos]
+		p.pos++
+		switch esc {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case '\n':
+			//JSON5允许用\换行
+		case 'u':
+			r, ok := p.hex4(p.pos)
+			if !ok {
+				return "", p.errorf("invalid unicode escape")
+			}
+			p.pos += 4
+			//UTF-16代理对由两个\uXXXX组成，需要合并为一个字符
+			if utf16.IsSurrogate(r) && p.pos+1 < len(p.data) && p.data[p.pos] == '\\' && p.data[p.pos+1] == 'u' {
+				if r2, ok := p.hex4(p.pos + 2); ok {
+					if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+						r = dec
+						p.pos += 6
+					}
+				}
+			}
+			var b [utf8.UTFMax]byte
+			buf.Write(b[:utf8.EncodeRune(b[:], r)])
+		default:
+			buf.WriteByte(esc)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+//解析pos处的4位十六进制数
+func (p *jsoncParser) hex4(pos int) (rune, bool) {
+	if pos+4 > len(p.data) {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(string(p.data[pos:pos+4]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(r), true
+}
+
+//数字保存为json.Number，十六进制等JSON5格式转换为标准JSON格式
+func (p *jsoncParser) parseNumber() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '+' || c == '-' || c == '.' || c == 'x' || c == 'X' || (c >= '0' && c <= '9') ||
+			(c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	s := strings.TrimPrefix(string(p.data[start:p.pos]), "+")
+	neg := strings.HasPrefix(s, "-")
+	if hex := strings.TrimPrefix(s, "-"); strings.HasPrefix(hex, "0x") || strings.HasPrefix(hex, "0X") {
+		v, err := strconv.ParseUint(hex[2:], 16, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", s)
+		}
+		s = strconv.FormatUint(v, 10)
+		if neg {
+			s = "-" + s
+		}
+		return json.Number(s), nil
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return nil, p.errorf("invalid number %q", s)
+	}
+	//JSON不允许小数点开头或结尾
+	s = strings.Replace(s, "-.", "-0.", 1)
+	if strings.HasPrefix(s, ".") {
+		s = "0" + s
+	}
+	if strings.HasSuffix(s, ".") {
+		s += "0"
+	}
+	s = strings.Replace(s, ".e", ".0e", 1)
+	s = strings.Replace(s, ".E", ".0E", 1)
+	return json.Number(s), nil
+}
+
+//按JSONC格式输出，缩进4个空格，key按字母排序，注释以//的形式写在配置项前面
+func marshalJsonc(data map[string]interface{}, comment map[string]string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJsonc(&buf, data, "", 0, comment); err != nil {
+		return nil, err
+	}
+	buf.WriteString(LINE_BREAK)
+	return buf.Bytes(), nil
+}
+
+func writeJsonc(buf *bytes.Buffer, val interface{}, path string, depth int, comment map[string]string) error {
+	indent := strings.Repeat("    ", depth+1)
+	writeComment := func(memberPath string) {
+		if comm, ok := comment[memberPath]; ok {
+			for _, line := range strings.Split(comm, LINE_BREAK) {
+				buf.WriteString(strings.TrimRight(indent+"// "+line, " ") + LINE_BREAK)
+			}
+		}
+		buf.WriteString(indent)
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{" + LINE_BREAK)
+		for i, k := range keys {
+			memberPath := joinPath(path, k)
+			writeComment(memberPath)
+			b, _ := json.Marshal(k)
+			buf.Write(b)
+			buf.WriteString(": ")
+			if err := writeJsonc(buf, v[k], memberPath, depth+1, comment); err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(LINE_BREAK)
+		}
+		buf.WriteString(strings.Repeat("    ", depth) + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[" + LINE_BREAK)
+		for i, elem := range v {
+			elemPath := joinPath(path, strconv.Itoa(i))
+			writeComment(elemPath)
+			if err := writeJsonc(buf, elem, elemPath, depth+1, comment); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(LINE_BREAK)
+		}
+		buf.WriteString(strings.Repeat("    ", depth) + "]")
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+func init() {
+	Register("jsonc", &JsoncConfig{}, ".jsonc")
+	Register("json5", &JsoncConfig{}, ".json5")
+}
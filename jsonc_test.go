@@ -0,0 +1,151 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var jsoncData = `
+{
+	// listen port
+	port: 8080, // http only
+	/*
+	 * database settings
+	 */
+	'mysql': {
+		"addr": '127.0.0.1',
+		passwd: "root", // plain text
+		slaves: [
+			// first slave
+			"192.168.1.2",
+			"192.168.1.3",
+		],
+	},
+}
+`
+
+func TestJsoncParse(t *testing.T) {
+	for _, name := range []string{"jsonc", "json5"} {
+		config, err := NewConfigData(name, []byte(jsoncData))
+		if err != nil {
+			t.Error(name, err)
+			return
+		}
+		if val, err := config.Int("port"); err != nil || val != 8080 {
+			t.Error(name, "Get int failed:", val, err)
+		}
+		if val := config.String("mysql::passwd"); val != "root" {
+			t.Error(name, "Get string failed:", val)
+		}
+		if val := config.Strings("mysql::slaves"); len(val) != 2 {
+			t.Error(name, "Get strings failed:", val)
+		}
+		if val := config.Comment("port"); val != "listen port\nhttp only" {
+			t.Error(name, "Get key comment failed:", val)
+		}
+		if val := config.SectionComment("mysql"); val != "database settings" {
+			t.Error(name, "Get section comment failed:", val)
+		}
+		if val := config.Comment("mysql::slaves::0"); val != "first slave" {
+			t.Error(name, "Get array comment failed:", val)
+		}
+	}
+}
+
+func TestJsoncInvalid(t *testing.T) {
+	for _, data := range []string{`{"a": 1`, `{"a" 1}`, `{"a": 'x}`, `{/* a: 1}`, `[1, 2]`, `{"a": 1} x`} {
+		if _, err := NewConfigData("jsonc", []byte(data)); err == nil {
+			t.Error("invalid jsonc should fail:", data)
+		}
+	}
+}
+
+func TestJsoncSave(t *testing.T) {
+	config, err := NewConfigData("jsonc", []byte(jsoncData))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	config.SetComment("mysql::addr", "primary")
+	filename := filepath.Join(t.TempDir(), "my.jsonc")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(string(data), "        // primary\n        \"addr\": \"127.0.0.1\",") {
+		t.Error("comment not saved:", string(data))
+	}
+
+	config, err = NewConfig("jsonc", filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.Comment("port"); val != "listen port\nhttp only" {
+		t.Error("key comment not preserved:", val)
+	}
+	if val := config.Comment("mysql::addr"); val != "primary" {
+		t.Error("edited comment not preserved:", val)
+	}
+	if val, err := config.Int("port"); err != nil || val != 8080 {
+		t.Error("value not preserved:", val, err)
+	}
+}
+
+func TestJsoncUnicodeEscape(t *testing.T) {
+	config, err := NewConfigData("jsonc", []byte(`{"emoji": "😀", "cjk": "中", "lone": "\uD83Dx"}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("emoji"); val != "\U0001F600" {
+		t.Errorf("surrogate pair not combined: %q", val)
+	}
+	if val := config.String("cjk"); val != "中" {
+		t.Errorf("Get unicode escape failed: %q", val)
+	}
+	if val := config.String("lone"); val != "�x" {
+		t.Errorf("lone surrogate got %q", val)
+	}
+}
+
+func TestJsoncOpeningComment(t *testing.T) {
+	data := "{\n\t\"mysql\": { // sec comment\n\t\t\"addr\": \"127.0.0.1\",\n\t\t\"slaves\": [ // replicas\n\t\t\t\"a\"\n\t\t]\n\t}\n}\n"
+	config, err := NewConfigData("jsonc", []byte(data))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.SectionComment("mysql"); val != "sec comment" {
+		t.Error("Get section comment failed:", val)
+	}
+	if val := config.Comment("mysql::addr"); val != "" {
+		t.Error("comment attached to first key:", val)
+	}
+	if val := config.Comment("mysql::slaves"); val != "replicas" {
+		t.Error("Get array comment failed:", val)
+	}
+	if val := config.Comment("mysql::slaves::0"); val != "" {
+		t.Error("comment attached to first element:", val)
+	}
+
+	filename := filepath.Join(t.TempDir(), "open.jsonc")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	if config, err = NewConfig("jsonc", filename); err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.SectionComment("mysql"); val != "sec comment" || config.Comment("mysql::addr") != "" {
+		t.Error("section comment moved on save:", val)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//配置文件的指纹，用于判断文件在解析之后是否被其他进程修改
+type Fingerprint struct {
+	Size    int64
+	ModTime time.Time
+	Hash    [sha256.Size]byte
+}
+
+//只比较大小和内容，只修改了mtime(比如touch)不算修改
+func (fp Fingerprint) same(other Fingerprint) bool {
+	return fp.Size == other.Size && fp.Hash == other.Hash
+}
+
+//保存时文件已经被其他进程修改，Expected为解析或者上次保存时的指纹，Actual为当前的指纹，文件不存在时为零值
+type ConflictError struct {
+	Filename string
+	Expected Fingerprint
+	Actual   Fingerprint
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("config file %s changed on disk since it was loaded (modified at %s), reload before saving",
+		e.Filename, e.Actual.ModTime.Format(time.RFC3339))
+}
+
+//记录配置对象来自的文件及其指纹，配置对象与其快照、事务共享
+type fileTracker struct {
+	filename string //绝对路径
+	fp       Fingerprint
+	locked   bool //EditFile已经持有文件锁，保存时不再加锁
+	sync.Mutex
+}
+
+//读取文件内容并记录指纹，指纹与读取的内容一致
+func readTrackedFile(filename string) ([]byte, *fileTracker, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, &fileTracker{filename: absPath(filename), fp: fingerprint(data, info)}, nil
+}
+
+func fingerprint(data []byte, info os.FileInfo) Fingerprint {
+	return Fingerprint{Size: info.Size(), ModTime: info.ModTime(), Hash: sha256.Sum256(data)}
+}
+
+func readFingerprint(filename string) (Fingerprint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return fingerprint(data, info), nil
+}
+
+//返回解析符号链接后的绝对路径，与保存时实际写入的文件一致
+func absPath(filename string) string {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		return abs
+	}
+	return filename
+}
+
+//保存配置；保存到配置来自的文件时在文件锁中先检查文件在解析之后是否被修改，被修改时返回*ConflictError。
+//保存到其他文件时只原子地替换文件，不加锁，也不会创建filename.lock
+func (t *fileTracker) save(filename string, backups int, w io.WriterTo) error {
+	if t == nil || t.filename != absPath(filename) {
+		return writeFileAtomic(filename, backups, w)
+	}
+	t.Lock()
+	defer t.Unlock()
+	if !t.locked {
+		unlock, err := lockFile(filename)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	actual, err := readFingerprint(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !actual.same(t.fp) {
+		return &ConflictError{Filename: filename, Expected: t.fp, Actual: actual}
+	}
+	if err = writeFileAtomic(filename, backups, w); err != nil {
+		return err
+	}
+	if t.fp, err = readFingerprint(filename); err != nil {
+		return err
+	}
+	return nil
+}
+
+//配置对象共享的fileTracker，由Parse创建
+type tracked interface {
+	tracker() *fileTracker
+}
+
+//在文件锁中加载、修改并保存配置文件，多个进程同时用EditFile修改同一个文件时依次执行，不会覆盖彼此的修改。
+//根据扩展名或者内容选择适配器，见Load；fn返回错误时不保存。锁加在filename.lock上，编辑完成后该文件保留
+func EditFile(filename string, fn func(c Configer) error, opts ...Option) error {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c, err := Load(filename, opts...)
+	if err != nil {
+		return err
+	}
+	if tc, ok := c.(tracked); ok {
+		//没有记录文件的配置保存时会再次加锁，同一个进程对同一个文件加两次flock会一直阻塞
+		if tc.tracker() == nil || tc.tracker().filename != absPath(filename) {
+			return errors.New("config loaded from " + filename + " is not tracked, can not edit it")
+		}
+		tc.tracker().locked = true
+		defer func() {
+			tc.tracker().Lock()
+			tc.tracker().locked = false
+			tc.tracker().Unlock()
+		}()
+	}
+	if err = fn(c); err != nil {
+		return err
+	}
+	return c.SaveConfigFile(filename)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package config
+
+//非unix系统不支持flock，只检查文件指纹
+func lockFile(filename string) (func(), error) {
+	return func() {}, nil
+}
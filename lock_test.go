@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSaveConflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.ini")
+	if err := ioutil.WriteFile(filename, []byte("port = 1\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err := NewConfig("ini", filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	config.Set("port", "2")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	//自己保存后再次保存不算冲突
+	config.Set("port", "3")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+
+	//其他进程修改了文件
+	if err = ioutil.WriteFile(filename, []byte("port = 10\nuser = root\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	config.Set("port", "4")
+	err = config.SaveConfigFile(filename)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Filename != filename {
+		t.Error("conflicting save should fail:", err)
+	}
+	if data, _ := ioutil.ReadFile(filename); string(data) != "port = 10\nuser = root\n" {
+		t.Error("conflicting save overwrote the file:", string(data))
+	}
+	tx := config.Begin()
+	tx.Set("port", "5")
+	if err = tx.CommitAndSave(filename); !errors.As(err, &conflict) {
+		t.Error("conflicting tx save should fail:", err)
+	}
+
+	//保存到其他文件不检查
+	if err = config.SaveConfigFile(filepath.Join(filepath.Dir(filename), "other.ini")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "counter.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"count": 0}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				err := EditFile(filename, func(c Configer) error {
+					n, err := c.Int("count")
+					if err != nil {
+						return err
+					}
+					return c.SetValue("count", n+1)
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	config, err := Load(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("count"); err != nil || val != 20 {
+		t.Error("concurrent edits lost:", val, err)
+	}
+	if err = EditFile(filename, func(c Configer) error {
+		c.SetValue("count", 0)
+		return errors.New("abort")
+	}); err == nil {
+		t.Error("error of fn should be returned")
+	}
+	if val, _ := Load(filename); val.String("count") != "20" {
+		t.Error("aborted edit saved:", val.String("count"))
+	}
+}
+
+func TestEditFileSniffed(t *testing.T) {
+	//扩展名未注册时根据内容选择适配器，保存时不能再次加锁
+	filename := filepath.Join(t.TempDir(), "app.settings")
+	if err := ioutil.WriteFile(filename, []byte("[a]\nx = 1\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- EditFile(filename, func(c Configer) error {
+			return c.Set("a::x", "2")
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("EditFile on sniffed file deadlocked")
+		return
+	}
+	if config, err := Load(filename); err != nil || config.String("a::x") != "2" {
+		t.Error("edit not saved:", err)
+	}
+}
+
+func TestSaveUntrackedNoLockFile(t *testing.T) {
+	config, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	filename := filepath.Join(t.TempDir(), "copy.ini")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = os.Stat(filename + ".lock"); !os.IsNotExist(err) {
+		t.Error("saving to another file should not leave a lock file:", err)
+	}
+}
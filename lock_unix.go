@@ -0,0 +1,33 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+//对filename加排他的建议锁(flock)，返回解锁函数。锁加在filename.lock上，
+//因为保存时rename会替换filename本身，加在filename上的锁对新文件无效。
+//filename.lock在解锁后保留，删除会让同时等待锁的进程锁住已经删除的文件；
+//只有EditFile以及保存到配置来自的文件时才会创建
+func lockFile(filename string) (func(), error) {
+	f, err := os.OpenFile(absPath(filename)+".lock", os.O_CREATE|os.O_RDWR, DEFAULT_FILE_MODE)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"sort"
+	"testing"
+)
+
+//每个注册的适配器都需要在这里提供内容相同的测试数据，
+//数值在JSON中尽量使用原生类型，以验证不同表示形式的转换结果一致
+var matrixData = map[string]string{
+	"ini": `
+port = 3306
+port_str = "3306"
+port_float = 3306.0
+port_exp = 1e3
+ratio = 3.5
+negative = -1
+max = 9223372036854775807
+overflow = 9223372036854775808
+open = true
+open_word = yes
+open_num = 1
+open_bad = 2
+`,
+	"json": `{
+	"port": 3306,
+	"port_str": "3306",
+	"port_float": 3306.0,
+	"port_exp": 1e3,
+	"ratio": 3.5,
+	"negative": -1,
+	"max": 9223372036854775807,
+	"overflow": 9223372036854775808,
+	"open": true,
+	"open_word": "yes",
+	"open_num": 1,
+	"open_bad": 2
+}`,
+	"jsonc": `{
+	// numbers
+	"port": 3306,
+	"port_str": "3306",
+	"port_float": 3306.0,
+	"port_exp": 1e3,
+	"ratio": 3.5, /* not an integer */
+	"negative": -1,
+	"max": 9223372036854775807,
+	"overflow": 9223372036854775808,
+	// bools
+	"open": true,
+	"open_word": "yes",
+	"open_num": 1,
+	"open_bad": 2,
+}`,
+	"json5": `{
+	port: 0xCEA,
+	port_str: '3306',
+	port_float: 3306.,
+	port_exp: +1e3,
+	ratio: 3.5,
+	negative: -1,
+	max: 9223372036854775807,
+	overflow: 9223372036854775808,
+	open: true,
+	open_word: 'yes',
+	open_num: 1,
+	open_bad: 2,
+}`,
+}
+
+type matrixCase struct {
+	key     string
+	get     func(c Configer, key string) (interface{}, error)
+	want    interface{}
+	wantErr bool
+}
+
+var (
+	getInt = func(c Configer, key string) (interface{}, error) {
+		return c.Int(key)
+	}
+	getInt64 = func(c Configer, key string) (interface{}, error) {
+		return c.Int64(key)
+	}
+	getFloat = func(c Configer, key string) (interface{}, error) {
+		return c.Float(key)
+	}
+	getBool = func(c Configer, key string) (interface{}, error) {
+		return c.Bool(key)
+	}
+	getString = func(c Configer, key string) (interface{}, error) {
+		return c.String(key), nil
+	}
+	getUint = func(c Configer, key string) (interface{}, error) {
+		return Get[uint](c, key)
+	}
+	getInt16 = func(c Configer, key string) (interface{}, error) {
+		return Get[int16](c, key)
+	}
+)
+
+var matrixCases = []matrixCase{
+	{"port", getInt, 3306, false},
+	{"port_str", getInt, 3306, false},
+	{"port_float", getInt, 3306, false},
+	{"port_exp", getInt64, int64(1000), false},
+	{"port", getInt64, int64(3306), false},
+	{"port_str", getInt64, int64(3306), false},
+	{"ratio", getInt, nil, true},
+	{"ratio", getInt64, nil, true},
+	{"ratio", getFloat, 3.5, false},
+	{"port", getFloat, 3306.0, false},
+	{"port", getString, "3306", false},
+	{"ratio", getString, "3.5", false},
+	{"max", getInt64, int64(9223372036854775807), false},
+	{"overflow", getInt64, nil, true},
+	{"negative", getInt, -1, false},
+	{"negative", getUint, nil, true},
+	{"port", getUint, uint(3306), false},
+	{"port", getInt16, int16(3306), false},
+	{"max", getInt16, nil, true},
+	{"open", getBool, true, false},
+	{"open_word", getBool, true, false},
+	{"open_num", getBool, true, false},
+	{"open_bad", getBool, nil, true},
+	{"open", getString, "true", false},
+	{"missing", getInt, nil, true},
+}
+
+func TestConversionMatrix(t *testing.T) {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, ok := matrixData[name]
+		if !ok {
+			t.Error("no matrix data for adapter", name)
+			continue
+		}
+		config, err := NewConfigData(name, []byte(data))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		for _, tc := range matrixCases {
+			got, err := tc.get(config, tc.key)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: %s should fail, got %v", name, tc.key, got)
+				}
+				continue
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("%s: %s got %v(%T) %v, want %v(%T)", name, tc.key, got, got, err, tc.want, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//以下getter基于Configer的GetString、GetInerfaceVal和Strings实现，适用于所有适配器，
+//切片版本中每个元素都会去掉首尾空白，返回的错误中包含配置项的名字
+
+//返回指定key对应的IP地址
+func GetIP(c Configer, key string) (net.IP, error) {
+	v, err := getNetString(c, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseIP(key, v)
+}
+
+func GetIPs(c Configer, key string) ([]net.IP, error) {
+	var ips []net.IP
+	vals, err := netStrings(c, key, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		ip, err := parseIP(key, v)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+//返回指定key对应的网段，比如"192.168.1.0/24"
+func GetCIDR(c Configer, key string) (*net.IPNet, error) {
+	v, err := getNetString(c, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseCIDR(key, v)
+}
+
+func GetCIDRs(c Configer, key string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	vals, err := netStrings(c, key, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		n, err := parseCIDR(key, v)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+//返回指定key对应的"host:port"，值中没有端口时使用defaultPort，
+//defaultPort为空时值中必须带端口，IPv6地址可以写成"::1"或者"[::1]:3306"
+func GetHostPort(c Configer, key, defaultPort string) (string, error) {
+	v, err := getNetString(c, key)
+	if err != nil {
+		return "", err
+	}
+	return parseHostPort(key, v, defaultPort)
+}
+
+func GetHostPorts(c Configer, key, defaultPort string) ([]string, error) {
+	var addrs []string
+	vals, err := netStrings(c, key, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		addr, err := parseHostPort(key, v, defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+//返回指定key对应的URL，URL必须包含scheme
+func GetURL(c Configer, key string) (*url.URL, error) {
+	v, err := getNetString(c, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseURL(key, v)
+}
+
+func GetURLs(c Configer, key string) ([]*url.URL, error) {
+	var urls []*url.URL
+	vals, err := netStrings(c, key, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		u, err := parseURL(key, v)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+//返回指定key对应的编译后的正则表达式
+func GetRegexp(c Configer, key string) (*regexp.Regexp, error) {
+	v, err := c.GetString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key, err)
+	}
+	return parseRegexp(key, v)
+}
+
+func GetRegexps(c Configer, key string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	vals, err := netStrings(c, key, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		re, err := parseRegexp(key, v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func getNetString(c Configer, key string) (string, error) {
+	v, err := c.GetString(key)
+	if err != nil {
+		return "", fmt.Errorf("key %s: %w", key, err)
+	}
+	v = strings.TrimSpace(v)
+	if len(v) == 0 {
+		return "", fmt.Errorf("key %s: empty value", key)
+	}
+	return v, nil
+}
+
+//按Strings的规则拆分列表，key不存在或者值无法展开时返回错误；trim为true时去掉首尾空白并忽略空元素
+func netStrings(c Configer, key string, trim bool) ([]string, error) {
+	//Strings不返回错误，先通过GetInerfaceVal检查key是否存在以及引用能否展开
+	if _, err := c.GetInerfaceVal(key); err != nil {
+		return nil, fmt.Errorf("key %s: %w", key, err)
+	}
+	if !trim {
+		return c.Strings(key), nil
+	}
+	var vals []string
+	for _, v := range c.Strings(key) {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals, nil
+}
+
+func parseIP(key, v string) (net.IP, error) {
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil, fmt.Errorf("key %s: invalid IP %q", key, v)
+	}
+	return ip, nil
+}
+
+func parseCIDR(key, v string) (*net.IPNet, error) {
+	_, n, err := net.ParseCIDR(v)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: invalid CIDR %q", key, v)
+	}
+	return n, nil
+}
+
+func parseHostPort(key, v, defaultPort string) (string, error) {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		//没有端口的情况，包括不带[]的IPv6地址
+		host, port = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]"), defaultPort
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", fmt.Errorf("key %s: invalid host:port %q", key, v)
+		}
+	}
+	if len(host) == 0 {
+		return "", fmt.Errorf("key %s: missing host in %q", key, v)
+	}
+	if len(port) == 0 {
+		return "", fmt.Errorf("key %s: missing port in %q", key, v)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		return "", fmt.Errorf("key %s: invalid port in %q", key, v)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func parseURL(key, v string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil || len(u.Scheme) == 0 {
+		return nil, fmt.Errorf("key %s: invalid URL %q", key, v)
+	}
+	return u, nil
+}
+
+func parseRegexp(key, v string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(v)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: invalid regexp %q: %w", key, v, err)
+	}
+	return re, nil
+}
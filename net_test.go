@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var netIni = `
+addr = "127.0.0.1"
+addrs = "127.0.0.1"; "192.168.1.1"
+bad = "300.0.0.1"
+subnet = 192.168.1.0/24
+v6 = ::1
+endpoint = http://127.0.0.1:8080/api
+pattern = ^/api/v[0-9]+
+[mysql]
+addr = 127.0.0.1:3307
+`
+
+func TestNetGetters(t *testing.T) {
+	config, err := NewConfigData("ini", []byte(netIni))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ip, err := GetIP(config, "addr"); err != nil || ip.String() != "127.0.0.1" {
+		t.Error("Get IP failed:", ip, err)
+	}
+	if ips, err := GetIPs(config, "addrs"); err != nil || len(ips) != 2 || ips[1].String() != "192.168.1.1" {
+		t.Error("Get IPs failed:", ips, err)
+	}
+	if _, err := GetIP(config, "bad"); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Error("error should name the key:", err)
+	}
+	if n, err := GetCIDR(config, "subnet"); err != nil || n.String() != "192.168.1.0/24" {
+		t.Error("Get CIDR failed:", n, err)
+	}
+	if addr, err := GetHostPort(config, "addr", "3306"); err != nil || addr != "127.0.0.1:3306" {
+		t.Error("Get host:port with default port failed:", addr, err)
+	}
+	if addr, err := GetHostPort(config, "mysql::addr", "3306"); err != nil || addr != "127.0.0.1:3307" {
+		t.Error("Get host:port failed:", addr, err)
+	}
+	if addr, err := GetHostPort(config, "v6", "80"); err != nil || addr != "[::1]:80" {
+		t.Error("Get IPv6 host:port failed:", addr, err)
+	}
+	if _, err := GetHostPort(config, "addr", ""); err == nil {
+		t.Error("missing port should fail")
+	}
+	if addrs, err := GetHostPorts(config, "addrs", "80"); err != nil || len(addrs) != 2 || addrs[1] != "192.168.1.1:80" {
+		t.Error("Get host:port list failed:", addrs, err)
+	}
+	if u, err := GetURL(config, "endpoint"); err != nil || u.Port() != "8080" {
+		t.Error("Get URL failed:", u, err)
+	}
+	if _, err := GetURL(config, "addr"); err == nil {
+		t.Error("URL without scheme should fail")
+	}
+	if re, err := GetRegexp(config, "pattern"); err != nil || !re.MatchString("/api/v2") {
+		t.Error("Get regexp failed:", re, err)
+	}
+}
+
+func TestNetListErrors(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("a = ${b}\nb = ${a}\nips = 127.0.0.1\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = GetIPs(config, "missing"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Error("missing key not reported:", err)
+	}
+	if _, err = GetIPs(config, "a"); !errors.Is(err, ErrInterpolationCycle) || !strings.Contains(err.Error(), "key a") {
+		t.Error("cycle not reported:", err)
+	}
+	if _, err = GetRegexps(config, "missing"); err == nil {
+		t.Error("missing key not reported")
+	}
+	if _, err = GetRegexp(config, "a"); !errors.Is(err, ErrInterpolationCycle) {
+		t.Error("cycle not wrapped:", err)
+	}
+	if ips, err := GetIPs(config, "ips"); err != nil || len(ips) != 1 {
+		t.Error("GetIPs failed:", ips, err)
+	}
+}
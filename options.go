@@ -0,0 +1,120 @@
+package config
+
+import (
+	"strings"
+)
+
+//解析和访问配置时的选项，保存在配置对象中，不同的配置对象可以使用不同的选项
+type Options struct {
+	CaseSensitive   bool     //查找key和section时是否区分大小写，默认不区分；保存时总是保留原始的大小写
+	KeySeparator    string   //section和key之间的分隔符，默认"::"
+	DefaultSection  string   //不在任何section下的配置项所属的section，默认DEFAULT_SECTION
+	CommentPrefixes []string //INI的注释符，默认"#"和";"，保存文件时使用第一个
+	Delimiters      []string //INI中key和value之间的分隔符，默认"="，保存文件时使用第一个
+	Strict          bool     //严格模式，重复的key或者section视为错误，默认后出现的覆盖先出现的
+	Backups         int      //SaveConfigFile覆盖文件前保留的备份个数，默认不备份
+}
+
+type Option func(*Options)
+
+//查找key和section时区分大小写，默认忽略大小写匹配，INI中只有大小写不同的section或key会被合并
+func WithCaseSensitive() Option {
+	return func(o *Options) {
+		o.CaseSensitive = true
+	}
+}
+
+func WithKeySeparator(sep string) Option {
+	return func(o *Options) {
+		if len(sep) > 0 {
+			o.KeySeparator = sep
+		}
+	}
+}
+
+func WithDefaultSection(section string) Option {
+	return func(o *Options) {
+		if len(section) > 0 {
+			o.DefaultSection = section
+		}
+	}
+}
+
+//设置INI的注释符，比如 WithCommentPrefixes("#", ";", "//")
+func WithCommentPrefixes(prefixes ...string) Option {
+	return func(o *Options) {
+		if len(prefixes) > 0 {
+			o.CommentPrefixes = prefixes
+		}
+	}
+}
+
+//设置INI中key和value之间的分隔符，比如 WithDelimiters("=", ":")，一行中最先出现的分隔符生效
+func WithDelimiters(delims ...string) Option {
+	return func(o *Options) {
+		if len(delims) > 0 {
+			o.Delimiters = delims
+		}
+	}
+}
+
+//SaveConfigFile覆盖已有文件前将原文件备份为filename.bak，更早的备份依次为filename.bak.1、filename.bak.2...，
+//最多保留n个备份
+func WithBackups(n int) Option {
+	return func(o *Options) {
+		o.Backups = n
+	}
+}
+
+func WithStrict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+//返回应用opts后的选项，未设置的选项取包级变量的当前值
+func newOptions(opts ...Option) Options {
+	o := Options{
+		KeySeparator:    "::",
+		DefaultSection:  DEFAULT_SECTION,
+		CommentPrefixes: []string{string(NUM_COMMENT), string(SEM_COMMENT)},
+		Delimiters:      []string{string(EQUAL)},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+//返回m中与k匹配的key，优先精确匹配，不区分大小写时其次忽略大小写匹配，都没有时返回k
+func matchKey[V any](o *Options, m map[string]V, k string) string {
+	if _, ok := m[k]; ok || o.CaseSensitive {
+		return k
+	}
+	for mk := range m {
+		if strings.EqualFold(mk, k) {
+			return mk
+		}
+	}
+	return k
+}
+
+//判断两个key或者section的名字是否相同
+func (o *Options) equal(a, b string) bool {
+	if o.CaseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+//返回配置对象使用的选项，不是本包创建的配置返回默认选项
+func configOptions(c Configer) *Options {
+	switch c := c.(type) {
+	case *IniConfigContainer:
+		return c.state.Load().opts
+	case *JsonCfgContainer:
+		return c.state.Load().opts
+	}
+	o := newOptions()
+	return &o
+}
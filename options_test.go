@@ -0,0 +1,202 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var optionsData = `
+; global settings
+Name: demo
+// database
+[MySQL]
+Addr = 127.0.0.1
+url: http://127.0.0.1:3306
+`
+
+func TestIniOptions(t *testing.T) {
+	config, err := NewConfigData("ini", []byte(optionsData),
+		WithCaseSensitive(),
+		WithKeySeparator("."),
+		WithDefaultSection("global"),
+		WithCommentPrefixes(";", "//"),
+		WithDelimiters("=", ":"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("Name"); val != "demo" {
+		t.Error("Get default section key failed:", val)
+	}
+	if val := config.String("MySQL.Addr"); val != "127.0.0.1" {
+		t.Error("Get key with separator failed:", val)
+	}
+	if val := config.String("mysql.addr"); val != "" {
+		t.Error("case sensitive key should not match:", val)
+	}
+	if val := config.String("MySQL.url"); val != "http://127.0.0.1:3306" {
+		t.Error("first delimiter should split the line:", val)
+	}
+	if val := config.SectionComment("MySQL"); val != "database" {
+		t.Error("Get section comment failed:", val)
+	}
+	if secs := config.Sections(); len(secs) != 2 || secs[0] != "global" {
+		t.Error("Get sections failed:", secs)
+	}
+
+	filename := filepath.Join(t.TempDir(), "options.ini")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(string(data), "; global settings\nName=demo\n") {
+		t.Error("options not used when saving:", string(data))
+	}
+
+	//默认选项不受其他配置对象的影响
+	config, err = NewConfigData("ini", []byte("[MySQL]\nAddr = 127.0.0.1\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("mysql::addr"); val != "127.0.0.1" {
+		t.Error("default options changed:", val)
+	}
+}
+
+func TestStrictOption(t *testing.T) {
+	cases := map[string]string{
+		"ini":   "[a]\nx = 1\n[b]\nx = 2\n[a]\ny = 1\n",
+		"json":  `{"a": {"x": 1, "y": [{"x": 1}, {"x": 2}], "x": 2}}`,
+		"jsonc": `{a: {x: 1, x: 2}}`,
+	}
+	for name, data := range cases {
+		if _, err := NewConfigData(name, []byte(data)); err != nil {
+			t.Error(name, "duplicate keys should be allowed by default:", err)
+		}
+		if _, err := NewConfigData(name, []byte(data), WithStrict()); err == nil {
+			t.Error(name, "duplicate keys should fail in strict mode")
+		}
+	}
+	if _, err := NewConfigData("ini", []byte("[a]\nx = 1\n[b]\nx = 2\n"), WithStrict()); err != nil {
+		t.Error("same key in different sections should be allowed:", err)
+	}
+	if _, err := NewConfigData("json", []byte(`{"a": [{"x": 1}, {"x": 2}], "x": 1}`), WithStrict()); err != nil {
+		t.Error("same key in different objects should be allowed:", err)
+	}
+}
+
+func TestJsonOptions(t *testing.T) {
+	config, err := NewConfigData("json", []byte(`{"MySQL": {"Addr": "127.0.0.1"}}`), WithCaseSensitive(), WithKeySeparator("/"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("MySQL/Addr"); val != "127.0.0.1" {
+		t.Error("Get key with separator failed:", val)
+	}
+	if val := config.String("mysql/addr"); val != "" {
+		t.Error("case sensitive key should not match:", val)
+	}
+	if err = config.SetValue("MySQL/Port", 3306); err != nil {
+		t.Error(err)
+	}
+	if val, err := config.Int("MySQL/Port"); err != nil || val != 3306 {
+		t.Error("Set with separator failed:", val, err)
+	}
+	if err = config.Delete("MySQL/Port"); err != nil || len(config.Keys("MySQL")) != 1 {
+		t.Error("Delete with separator failed:", err, config.Keys("MySQL"))
+	}
+}
+
+var mixedCaseIni = `
+IsOpen = true
+[MySQL]
+Addr = 127.0.0.1
+[mysql]
+Port = 3306
+`
+
+func TestCasePreserving(t *testing.T) {
+	config, err := NewConfigData("ini", []byte(mixedCaseIni))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if secs := config.Sections(); strings.Join(secs, ",") != "default,MySQL" {
+		t.Error("sections differing only in case should be merged:", secs)
+	}
+	if keys := config.Keys("mysql"); strings.Join(keys, ",") != "Addr,Port" {
+		t.Error("Get keys failed:", keys)
+	}
+	if val, err := config.Bool("isopen"); err != nil || !val {
+		t.Error("case insensitive lookup failed:", val, err)
+	}
+	config.Set("MYSQL::ADDR", "localhost")
+	config.Set("mysql::User", "root")
+	filename := filepath.Join(t.TempDir(), "case.ini")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(string(data), "IsOpen=true\n[MySQL]\nAddr=localhost\nPort=3306\nUser=root\n") {
+		t.Error("case not preserved when saving:", string(data))
+	}
+
+	config, err = NewConfigData("ini", []byte(mixedCaseIni), WithCaseSensitive())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if secs := config.Sections(); strings.Join(secs, ",") != "default,MySQL,mysql" {
+		t.Error("case sensitive sections should not be merged:", secs)
+	}
+	if val := config.String("mysql::Addr"); val != "" {
+		t.Error("case sensitive lookup should fail:", val)
+	}
+}
+
+func TestJsonCaseInsensitive(t *testing.T) {
+	for _, name := range []string{"json", "jsonc"} {
+		config, err := NewConfigData(name, []byte(`{"IsOpen": true, "MySQL": {"Addr": "127.0.0.1"}}`))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if sec, err := config.GetSection("mysql"); err != nil || sec["Addr"] != "127.0.0.1" {
+			t.Error(name, "Get mixed case section failed:", sec, err)
+		}
+		if keys := config.Keys("DEFAULT"); strings.Join(keys, ",") != "IsOpen" {
+			t.Error(name, "Get default keys failed:", keys)
+		}
+		if !config.HasSection("MYSQL") || config.SectionComment("mysql") != "" {
+			t.Error(name, "HasSection failed")
+		}
+	}
+}
+
+func TestCommentPrefixString(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("REMEMBER me\nport = 80\n## twice\naddr = x\n"), WithCommentPrefixes("REM", "#"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.Comment("port"); val != "EMBER me" {
+		t.Error("comment prefix treated as a character set:", val)
+	}
+	if val := config.Comment("addr"); val != "twice" {
+		t.Error("repeated prefix not removed:", val)
+	}
+}
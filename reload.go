@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//收到信号时重新加载配置，校验失败时保留原来的配置，并记录最近一次加载的时间和错误
+type Reloader struct {
+	config     Configer
+	signals    []os.Signal
+	validators []Validator
+	logger     *log.Logger
+	lastTime   time.Time
+	lastErr    error
+	sync.Mutex //保证同一时间只有一次加载
+}
+
+type ReloaderOption func(*Reloader)
+
+//设置触发加载的信号，默认SIGHUP
+func WithSignals(sigs ...os.Signal) ReloaderOption {
+	return func(r *Reloader) {
+		if len(sigs) > 0 {
+			r.signals = sigs
+		}
+	}
+}
+
+//加载后、替换配置前依次执行validators，在配置的写锁中执行，validators中不能修改配置
+func WithValidators(validators ...Validator) ReloaderOption {
+	return func(r *Reloader) {
+		r.validators = append(r.validators, validators...)
+	}
+}
+
+//设置记录加载结果的logger，默认log.Default()，为nil时不记录
+func WithLogger(logger *log.Logger) ReloaderOption {
+	return func(r *Reloader) {
+		r.logger = logger
+	}
+}
+
+func NewReloader(config Configer, opts ...ReloaderOption) *Reloader {
+	r := &Reloader{
+		config:  config,
+		signals: []os.Signal{syscall.SIGHUP},
+		logger:  log.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+//监听信号，每收到一次信号加载一次配置，ctx结束时停止监听并返回
+func (r *Reloader) Run(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, r.signals...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			r.Reload()
+		}
+	}
+}
+
+//立即加载一次配置，返回的错误同时记录为LastReload的结果
+func (r *Reloader) Reload() error {
+	r.Lock()
+	defer r.Unlock()
+	var events []Event
+	var err error
+	if c, ok := r.config.(interface {
+		reload(validators []Validator) ([]Event, error)
+	}); ok {
+		events, err = c.reload(r.validators)
+	} else {
+		err = r.config.Reload(r.validators...)
+	}
+	r.lastTime, r.lastErr = time.Now(), err
+	r.log(events, err)
+	return err
+}
+
+//返回最近一次加载的时间和错误，还没有加载过时返回零值
+func (r *Reloader) LastReload() (time.Time, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.lastTime, r.lastErr
+}
+
+//只记录变化的key，不记录值，避免密码等写入日志
+func (r *Reloader) log(events []Event, err error) {
+	if r.logger == nil {
+		return
+	}
+	if err != nil {
+		r.logger.Printf("config: reload failed, keeping previous config: %v", err)
+		return
+	}
+	r.logger.Printf("config: reloaded, %d key(s) changed", len(events))
+	for _, e := range events {
+		r.logger.Printf("config: %s %s", e.Type, e.Key)
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.ini")
+	if err := ioutil.WriteFile(filename, []byte("[mysql]\nport = 3306\npasswd = root\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err := NewConfig("ini", filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	r := NewReloader(config, WithLogger(log.New(&buf, "", 0)), WithValidators(func(c Configer) error {
+		if port, err := c.Int("mysql::port"); err != nil || port <= 0 {
+			return errors.New("invalid mysql port")
+		}
+		return nil
+	}))
+	if at, err := r.LastReload(); !at.IsZero() || err != nil {
+		t.Error("unexpected last reload:", at, err)
+	}
+
+	ioutil.WriteFile(filename, []byte("[mysql]\nport = 3307\npasswd = secret\n"), 0644)
+	if err = r.Reload(); err != nil {
+		t.Error(err)
+	}
+	if port, _ := config.Int("mysql::port"); port != 3307 {
+		t.Error("reload failed:", port)
+	}
+	if out := buf.String(); !strings.Contains(out, "updated mysql::passwd") || strings.Contains(out, "secret") {
+		t.Error("unexpected log:", out)
+	}
+
+	ioutil.WriteFile(filename, []byte("[mysql]\nport = 0\n"), 0644)
+	if err = r.Reload(); err == nil {
+		t.Error("invalid config should be rejected")
+	}
+	if at, last := r.LastReload(); at.IsZero() || last != err {
+		t.Error("last reload not recorded:", at, last)
+	}
+	if port, _ := config.Int("mysql::port"); port != 3307 || config.String("mysql::passwd") != "secret" {
+		t.Error("previous config not kept:", port)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	ioutil.WriteFile(filename, []byte("[mysql]\nport = 3308\n"), 0644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := config.Watch(ctx, "mysql::port")
+	//先监听SIGHUP，避免Run开始监听前收到信号时进程退出
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go r.Run(ctx)
+	deadline := time.Now().Add(time.Second)
+	for {
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(syscall.SIGHUP)
+		select {
+		case e := <-ch:
+			if e.NewValue != "3308" {
+				t.Errorf("got %+v", e)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Error("SIGHUP did not reload config")
+			return
+		}
+	}
+}
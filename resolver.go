@@ -0,0 +1,177 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+//值解析器，将 scheme://ref 形式的配置值解析为实际的值，比如
+//
+//	passwd = file:///run/secrets/db   读取文件内容
+//	passwd = env://DB_PASS            读取环境变量
+//
+//只有已注册scheme的值才会被解析，其他值(比如http://...)原样返回
+type Resolver interface {
+	Resolve(ref string) (string, error) //ref为scheme://之后的部分
+}
+
+//ResolverFunc 将普通函数适配为Resolver
+type ResolverFunc func(ref string) (string, error)
+
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+const SCHEME_SEP = "://"
+
+var (
+	resolverMu sync.RWMutex
+	resolvers  = make(map[string]Resolver)
+	cache      = &resolverCache{entries: make(map[string]cacheEntry)}
+)
+
+//注册scheme对应的解析器，与适配器一样每个scheme只允许注册一次
+func RegisterResolver(scheme string, resolver Resolver) {
+	if resolver == nil {
+		panic("Config: resolver can not be empty")
+	}
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	scheme = strings.ToLower(scheme)
+	if _, ok := resolvers[scheme]; ok {
+		panic("Config: resolver for scheme:" + scheme + " is only allowed to register once")
+	}
+	resolvers[scheme] = resolver
+}
+
+//设置解析结果的缓存时间，0表示永不过期(默认)，负数表示不缓存。
+//修改ttl会清空已缓存的结果
+func SetResolverTTL(ttl time.Duration) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.ttl = ttl
+	cache.entries = make(map[string]cacheEntry)
+}
+
+//清空解析结果的缓存，比如轮换密钥之后
+func ClearResolverCache() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.entries = make(map[string]cacheEntry)
+}
+
+//解析scheme://ref形式的值，未注册的scheme原样返回
+func resolveValue(val string) (string, error) {
+	idx := strings.Index(val, SCHEME_SEP)
+	if idx <= 0 {
+		return val, nil
+	}
+	resolverMu.RLock()
+	resolver, ok := resolvers[strings.ToLower(val[:idx])]
+	resolverMu.RUnlock()
+	if !ok {
+		return val, nil
+	}
+
+	if v, ok := cache.get(val); ok {
+		return v, nil
+	}
+	v, err := resolver.Resolve(val[idx+len(SCHEME_SEP):])
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", val, err)
+	}
+	cache.set(val, v)
+	return v, nil
+}
+
+type cacheEntry struct {
+	val     string
+	expires time.Time
+}
+
+type resolverCache struct {
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	sync.Mutex
+}
+
+func (rc *resolverCache) get(key string) (string, bool) {
+	rc.Lock()
+	defer rc.Unlock()
+	e, ok := rc.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(rc.entries, key)
+		return "", false
+	}
+	return e.val, true
+}
+
+func (rc *resolverCache) set(key, val string) {
+	rc.Lock()
+	defer rc.Unlock()
+	if rc.ttl < 0 {
+		return
+	}
+	e := cacheEntry{val: val}
+	if rc.ttl > 0 {
+		e.expires = time.Now().Add(rc.ttl)
+	}
+	rc.entries[key] = e
+}
+
+//file:///path 读取文件内容，去掉末尾的换行
+func resolveFile(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+//env://NAME 读取环境变量，环境变量不存在时返回错误
+func resolveEnv(ref string) (string, error) {
+	if v, ok := os.LookupEnv(ref); ok {
+		return v, nil
+	}
+	return "", errors.New("environment variable " + ref + " not set")
+}
+
+//exec://command args... 执行命令并返回其标准输出，去掉末尾的换行。
+//由于能修改配置文件的人就能执行任意命令，exec默认不注册，需要时调用
+//RegisterResolver("exec", &ExecResolver{})
+type ExecResolver struct {
+	Timeout time.Duration //命令执行的超时时间，为0时不超时
+}
+
+func (er *ExecResolver) Resolve(ref string) (string, error) {
+	args := strings.Fields(ref)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	ctx := context.Background()
+	if er.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, er.Timeout)
+		defer cancel()
+	}
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+func init() {
+	RegisterResolver("file", ResolverFunc(resolveFile))
+	RegisterResolver("env", ResolverFunc(resolveEnv))
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveFileAndEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolver")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	secret := filepath.Join(dir, "db")
+	if err = ioutil.WriteFile(secret, []byte("s3cret\n"), 0600); err != nil {
+		t.Error(err)
+		return
+	}
+	os.Setenv("RESOLVER_TEST_PORT", "3307")
+	defer os.Unsetenv("RESOLVER_TEST_PORT")
+
+	data := "[mysql]\npasswd = file://" + secret + "\nport = env://RESOLVER_TEST_PORT\n" +
+		"missing = env://RESOLVER_TEST_MISSING\nurl = http://127.0.0.1\ndsn = root:${mysql::passwd}@tcp\n"
+	config, err := NewConfigData("ini", []byte(data))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if val := config.String("mysql::passwd"); val != "s3cret" {
+		t.Error("file resolver failed:", val)
+	}
+	if val := config.RawString("mysql::passwd"); val != "file://"+secret {
+		t.Error("raw string should not be resolved:", val)
+	}
+	if val, err := config.Int("mysql::port"); err != nil || val != 3307 {
+		t.Error("env resolver failed:", val, err)
+	}
+	if val := config.String("mysql::url"); val != "http://127.0.0.1" {
+		t.Error("unknown scheme should be kept:", val)
+	}
+	if val := config.String("mysql::dsn"); val != "root:s3cret@tcp" {
+		t.Error("reference to secret failed:", val)
+	}
+	if _, err := config.GetString("mysql::missing"); err == nil {
+		t.Error("resolver error not surfaced")
+	}
+	if val := config.DefaultString("mysql::missing", "none"); val != "none" {
+		t.Error("default value on resolver error failed:", val)
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	calls := 0
+	RegisterResolver("counter", ResolverFunc(func(ref string) (string, error) {
+		calls++
+		return ref, nil
+	}))
+	defer SetResolverTTL(0)
+
+	config, err := NewConfigData("json", []byte(`{"token": "counter://abc"}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	config.String("token")
+	config.String("token")
+	if calls != 1 {
+		t.Error("resolved value not cached:", calls)
+	}
+
+	SetResolverTTL(time.Nanosecond)
+	config.String("token")
+	time.Sleep(time.Millisecond)
+	if val := config.String("token"); val != "abc" || calls != 3 {
+		t.Error("cache ttl not honoured:", val, calls)
+	}
+}
+
+func TestResolveErrorChain(t *testing.T) {
+	config, err := NewConfigData("ini", []byte("secret = file:///nonexistent/config/secret\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = config.GetString("secret"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("error chain lost:", err)
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//新建的配置文件的权限，覆盖已有文件时保留原文件的权限
+var DEFAULT_FILE_MODE os.FileMode = 0644
+
+//原子地将w的内容写入filename：先写入同一目录下的临时文件并fsync，再rename覆盖filename，
+//写入过程中崩溃时filename保持原来的内容。filename已存在时保留其权限和属主，
+//filename为符号链接时写入链接指向的文件。backups大于0时rename前备份原文件，见WithBackups
+func writeFileAtomic(filename string, backups int, w io.WriterTo) (err error) {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	info, statErr := os.Stat(filename)
+	if statErr != nil && !os.IsNotExist(statErr) {
+		return statErr
+	}
+
+	dir, base := filepath.Split(filename)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = w.WriteTo(tmp); err != nil {
+		return err
+	}
+	mode := DEFAULT_FILE_MODE
+	if statErr == nil {
+		mode = info.Mode().Perm()
+		if err = chownLike(tmp, info); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if statErr == nil && backups > 0 {
+		if err = rotateBackups(filename, backups); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+//filename的第i个备份，0为最新的备份
+func backupName(filename string, i int) string {
+	if i == 0 {
+		return filename + ".bak"
+	}
+	return fmt.Sprintf("%s.bak.%d", filename, i)
+}
+
+//将已有的备份依次后移，删除超过n个的备份，再将filename备份为filename.bak
+func rotateBackups(filename string, n int) error {
+	if err := os.Remove(backupName(filename, n-1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := n - 2; i >= 0; i-- {
+		if err := os.Rename(backupName(filename, i), backupName(filename, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	//优先使用硬链接，随后的rename不会修改原文件，因此备份就是覆盖前的内容
+	bak := backupName(filename, 0)
+	if err := os.Link(filename, bak); err == nil {
+		return nil
+	}
+	return copyFile(filename, bak)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package config
+
+import (
+	"os"
+)
+
+func chownLike(f *os.File, info os.FileInfo) error {
+	return nil
+}
+
+func syncDir(dir string) error {
+	return nil
+}
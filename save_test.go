@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveConfigFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.ini")
+	if err := ioutil.WriteFile(filename, []byte("port = 1\n"), 0600); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err := NewConfig("ini", filename, WithBackups(2))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, port := range []string{"2", "3", "4"} {
+		config.Set("port", port)
+		if err = config.SaveConfigFile(filename); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Error("file mode not preserved:", info.Mode(), err)
+	}
+	for name, want := range map[string]string{
+		filename:                 "port=4\n",
+		backupName(filename, 0): "port=3\n",
+		backupName(filename, 1): "port=2\n",
+	} {
+		if data, err := ioutil.ReadFile(name); err != nil || string(data) != want {
+			t.Errorf("%s got %q %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err = os.Stat(backupName(filename, 2)); !os.IsNotExist(err) {
+		t.Error("old backups should be removed:", err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := config.WriteTo(&buf); err != nil || n != int64(buf.Len()) || buf.String() != "port=4\n" {
+		t.Error("WriteTo failed:", n, buf.String(), err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) WriteTo(w io.Writer) (int64, error) {
+	w.Write([]byte("partial"))
+	return 0, errors.New("write failed")
+}
+
+func TestSaveConfigFileFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"port": 1}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := writeFileAtomic(filename, 1, failWriter{}); err == nil {
+		t.Error("write error should be returned")
+	}
+	if data, err := ioutil.ReadFile(filename); err != nil || string(data) != `{"port": 1}` {
+		t.Error("original file changed:", string(data), err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Error("temp file not removed:", len(files))
+	}
+
+	//保存到符号链接时替换链接指向的文件
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(filename, link); err != nil {
+		t.Skip(err)
+	}
+	config, err := NewConfigData("json", []byte(`{"port": 2}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.SaveConfigFile(link); err != nil {
+		t.Error(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink replaced:", err)
+	}
+	if data, err := ioutil.ReadFile(filename); err != nil || string(data) != `{"port":2}` {
+		t.Error("symlink target not saved:", string(data), err)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+//将f的属主设置为与info相同，非root用户无法修改属主，此时保留当前用户为属主，不返回错误
+func chownLike(f *os.File, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if err := f.Chown(int(st.Uid), int(st.Gid)); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
+
+//fsync目录，保证rename在崩溃后仍然生效
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
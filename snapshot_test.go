@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	for _, name := range []string{"ini", "json", "jsonc"} {
+		config, err := NewConfigData(name, []byte(matrixData[name]))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		snap := config.Snapshot()
+		config.Set("port", "8080")
+		config.Delete("ratio")
+
+		if val, err := snap.Int("port"); err != nil || val != 3306 {
+			t.Error(name, "snapshot changed after Set:", val, err)
+		}
+		if val, err := snap.Float("ratio"); err != nil || val != 3.5 {
+			t.Error(name, "snapshot changed after Delete:", val, err)
+		}
+		if val, err := config.Int("port"); err != nil || val != 8080 {
+			t.Error(name, "Set failed:", val, err)
+		}
+		if err = snap.Set("port", "1"); !errors.Is(err, ErrReadOnly) {
+			t.Error(name, "snapshot should be read-only:", err)
+		}
+		if err = snap.Snapshot().SetComment("port", "x"); !errors.Is(err, ErrReadOnly) {
+			t.Error(name, "snapshot of snapshot should be read-only:", err)
+		}
+
+		err = config.View(func(c Configer) error {
+			config.Set("port", "9090")
+			if val, err := c.Int("port"); err != nil || val != 8080 {
+				t.Error(name, "view changed during reads:", val, err)
+			}
+			return errors.New("stop")
+		})
+		if err == nil || err.Error() != "stop" {
+			t.Error(name, "View should return the error of fn:", err)
+		}
+	}
+}
+
+//读取不加锁，因此不能把锁暴露给调用方，否则调用方会以为RLock能保证多次读取一致
+func TestLockNotExported(t *testing.T) {
+	for _, name := range []string{"ini", "json", "jsonc"} {
+		config, err := NewConfigData(name, []byte(matrixData[name]))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if _, ok := config.(sync.Locker); ok {
+			t.Error(name, "config should not expose its lock, use Snapshot or View instead")
+		}
+	}
+}
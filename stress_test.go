@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+//对每个注册的适配器并发执行Set/Get/Save等操作，配合go test -race检查数据竞争
+func TestConcurrentAccess(t *testing.T) {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config, err := NewConfigData(name, []byte(matrixData[name]))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		dir := t.TempDir()
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				key := fmt.Sprintf("stress::key%d", g)
+				for i := 0; i < 50; i++ {
+					if err := config.Set(key, fmt.Sprint(i)); err != nil {
+						t.Error(name, err)
+						return
+					}
+					if err := config.SetValue("port", 3306); err != nil {
+						t.Error(name, err)
+						return
+					}
+					if val, err := config.Int("port"); err != nil || val != 3306 {
+						t.Error(name, "Get int failed:", val, err)
+						return
+					}
+					config.String(key)
+					config.SetComment(key, "comment")
+					config.Comment(key)
+					config.Keys("stress")
+					config.Sections()
+
+					//修改返回的副本不影响配置
+					if sec, err := config.GetSection("stress"); err == nil {
+						sec[key] = "changed"
+					}
+					if val, err := config.GetInerfaceVal("stress"); err == nil {
+						switch m := val.(type) {
+						case map[string]string:
+							m[key] = "changed"
+						case map[string]interface{}:
+							m[key] = "changed"
+						}
+					}
+					switch data := config.GetCfgData().(type) {
+					case map[string]map[string]string:
+						data["stress"] = nil
+					case map[string]interface{}:
+						delete(data, "stress")
+					}
+
+					if i%10 == 0 {
+						if err := config.SaveConfigFile(filepath.Join(dir, fmt.Sprintf("%d.%s", g, name))); err != nil {
+							t.Error(name, err)
+							return
+						}
+						config.Delete(key)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		for g := 0; g < 8; g++ {
+			key := fmt.Sprintf("stress::key%d", g)
+			if val := config.String(key); val != "49" {
+				t.Error(name, "last write lost:", key, val)
+			}
+		}
+	}
+}
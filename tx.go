@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrTxDone     = errors.New("transaction has already been committed or rolled back")
+	ErrTxConflict = errors.New("config changed after the transaction began")
+)
+
+//提交事务前检查修改后的配置，返回错误时不提交
+type Validator func(c Configer) error
+
+//事务，Begin返回的事务在配置的副本上修改，提交时整体替换配置，读取方只会看到提交前或者提交后的配置。
+//事务开始后配置被其他写入修改时，提交返回ErrTxConflict
+type Tx interface {
+	Set(key, val string) error
+	SetValue(key string, val interface{}) error
+	Delete(key string) error
+	Config() Configer                                             //返回包含未提交修改的只读配置
+	Commit(validators ...Validator) error                         //依次执行validators，全部通过后提交
+	CommitAndSave(filename string, validators ...Validator) error //与Commit相同，提交前先保存到filename，保存失败时不提交
+	Rollback() error                                              //放弃全部修改
+}
+
+type configTx struct {
+	work Configer
+	//在配置的写锁中执行save，成功后用work替换配置
+	commit func(save func() error) error
+	done   bool
+	sync.Mutex
+}
+
+func newTx(work Configer, commit func(save func() error) error) *configTx {
+	return &configTx{work: work, commit: commit}
+}
+
+func (t *configTx) Set(key, val string) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.done {
+		return ErrTxDone
+	}
+	return t.work.Set(key, val)
+}
+
+func (t *configTx) SetValue(key string, val interface{}) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.done {
+		return ErrTxDone
+	}
+	return t.work.SetValue(key, val)
+}
+
+func (t *configTx) Delete(key string) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.done {
+		return ErrTxDone
+	}
+	return t.work.Delete(key)
+}
+
+func (t *configTx) Config() Configer {
+	return t.work.Snapshot()
+}
+
+func (t *configTx) Commit(validators ...Validator) error {
+	return t.commitAndSave("", validators)
+}
+
+func (t *configTx) CommitAndSave(filename string, validators ...Validator) error {
+	if len(filename) == 0 {
+		return errors.New("filename can not be empty")
+	}
+	return t.commitAndSave(filename, validators)
+}
+
+//校验或者提交失败时事务保持未完成，可以继续修改后重新提交，或者调用Rollback
+func (t *configTx) commitAndSave(filename string, validators []Validator) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.done {
+		return ErrTxDone
+	}
+	snap := t.work.Snapshot()
+	for _, validate := range validators {
+		if err := validate(snap); err != nil {
+			return err
+		}
+	}
+	err := t.commit(func() error {
+		if len(filename) == 0 {
+			return nil
+		}
+		return snap.SaveConfigFile(filename)
+	})
+	if err != nil {
+		return err
+	}
+	t.done = true
+	return nil
+}
+
+func (t *configTx) Rollback() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func requirePort(c Configer) error {
+	if port, err := c.Int("mysql::port"); err != nil || port <= 0 {
+		return errors.New("invalid mysql::port")
+	}
+	return nil
+}
+
+func TestTxCommit(t *testing.T) {
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		tx := config.Begin()
+		tx.Set("mysql::addr", "10.0.0.1")
+		tx.SetValue("mysql::port", 3307)
+		tx.Delete("mysql::passwd")
+		if val := config.String("mysql::addr"); val != "127.0.0.1" {
+			t.Error(name, "uncommitted change is visible:", val)
+		}
+		if val := tx.Config().String("mysql::addr"); val != "10.0.0.1" {
+			t.Error(name, "tx config should see pending changes:", val)
+		}
+
+		filename := filepath.Join(t.TempDir(), "tx."+name)
+		if err = tx.CommitAndSave(filename, requirePort); err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if val, err := config.Int("mysql::port"); err != nil || val != 3307 || config.String("mysql::passwd") != "" {
+			t.Error(name, "commit failed:", val, err)
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil || !strings.Contains(string(data), "10.0.0.1") {
+			t.Error(name, "commit not saved:", string(data), err)
+		}
+		if err = tx.Set("mysql::addr", "x"); err != ErrTxDone {
+			t.Error(name, "committed tx should be done:", err)
+		}
+	}
+}
+
+func TestTxValidateAndRollback(t *testing.T) {
+	config, err := NewConfig("ini", "my.ini")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tx := config.Begin()
+	tx.Set("mysql::port", "-1")
+	tx.Set("mysql::addr", "10.0.0.1")
+	filename := filepath.Join(t.TempDir(), "tx.ini")
+	if err = tx.CommitAndSave(filename, requirePort); err == nil {
+		t.Error("invalid config should not be committed")
+	}
+	if _, err = ioutil.ReadFile(filename); err == nil {
+		t.Error("invalid config should not be saved")
+	}
+	if val := config.String("mysql::addr"); val != "127.0.0.1" {
+		t.Error("failed commit changed config:", val)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Error(err)
+	}
+	if err = tx.Commit(); err != ErrTxDone {
+		t.Error("rolled back tx should be done:", err)
+	}
+}
+
+func TestTxConflict(t *testing.T) {
+	config, err := NewConfig("json", "my.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tx := config.Begin()
+	tx.Set("mysql::addr", "10.0.0.1")
+	config.Set("mysql::user", "admin")
+	if err = tx.Commit(); err != ErrTxConflict {
+		t.Error("concurrent change should conflict:", err)
+	}
+	if val := config.String("mysql::addr"); val != "127.0.0.1" {
+		t.Error("conflicting tx changed config:", val)
+	}
+	if err = config.Snapshot().Begin().Commit(); !errors.Is(err, ErrReadOnly) {
+		t.Error("tx on snapshot should fail:", err)
+	}
+}
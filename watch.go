@@ -0,0 +1,242 @@
+package config
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type EventType int
+
+const (
+	EventCreated EventType = iota + 1
+	EventUpdated
+	EventDeleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+//配置项的变化，由Set、Delete、事务提交以及Reload产生
+type Event struct {
+	Type     EventType
+	Section  string //配置项所在的section，不在section下的配置项为DEFAULT_SECTION
+	Key      string //配置项的完整路径，比如 mysql::port，可以直接用于String等读取
+	OldValue string //原始值，不展开${...}引用，Type为EventCreated时为空
+	NewValue string //原始值，Type为EventDeleted时为空
+}
+
+//展开后的配置项，key为Event.Key
+type flatValue struct {
+	section string
+	value   string
+	raw     interface{} //配置中的原始类型，Diff按类型比较时使用
+}
+
+//比较展开后的两份配置，返回按Key排序的变化
+func diffFlat(old, cur map[string]flatValue) []Event {
+	var events []Event
+	for key, nv := range cur {
+		if ov, ok := old[key]; !ok {
+			events = append(events, Event{Type: EventCreated, Section: nv.section, Key: key, NewValue: nv.value})
+		} else if ov.value != nv.value {
+			events = append(events, Event{Type: EventUpdated, Section: nv.section, Key: key, OldValue: ov.value, NewValue: nv.value})
+		}
+	}
+	for key, ov := range old {
+		if _, ok := cur[key]; !ok {
+			events = append(events, Event{Type: EventDeleted, Section: ov.section, Key: key, OldValue: ov.value})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
+
+//将INI配置展开为 key-->值，DEFAULT_SECTION下的配置项key不带section
+func (s *iniState) flatten() map[string]flatValue {
+	out := make(map[string]flatValue)
+	def := s.sectionName(s.opts.DefaultSection)
+	for sec, m := range s.data {
+		for k, v := range m {
+			key := k
+			if sec != def {
+				key = sec + s.opts.KeySeparator + k
+			}
+			out[key] = flatValue{section: sec, value: v, raw: v}
+		}
+	}
+	return out
+}
+
+//将JSON配置展开为 路径-->值，对象和数组展开到叶子节点，值不是对象的顶层配置项属于DEFAULT_SECTION
+func (s *jsonState) flatten() map[string]flatValue {
+	out := make(map[string]flatValue)
+	var walk func(section, key string, val interface{})
+	walk = func(section, key string, val interface{}) {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			for k, vv := range v {
+				walk(section, key+s.opts.KeySeparator+k, vv)
+			}
+		case []interface{}:
+			for i, vv := range v {
+				walk(section, key+s.opts.KeySeparator+strconv.Itoa(i), vv)
+			}
+		case nil:
+			out[key] = flatValue{section: section}
+		default:
+			out[key] = flatValue{section: section, value: ToString(v), raw: v}
+		}
+	}
+	for k, v := range s.data {
+		section := s.opts.DefaultSection
+		if _, ok := v.(map[string]interface{}); ok {
+			section = k
+		}
+		walk(section, k, v)
+	}
+	return out
+}
+
+//按sep拆分后逐段用path.Match匹配，最后一段为"**"时匹配剩余的任意多段
+func matchPattern(pattern, key string, o *Options) bool {
+	if !o.CaseSensitive {
+		pattern, key = strings.ToLower(pattern), strings.ToLower(key)
+	}
+	ps, ks := strings.Split(pattern, o.KeySeparator), strings.Split(key, o.KeySeparator)
+	for i, p := range ps {
+		if p == "**" && i == len(ps)-1 {
+			return true
+		}
+		if i >= len(ks) {
+			return false
+		}
+		if ok, _ := path.Match(p, ks[i]); !ok {
+			return false
+		}
+	}
+	return len(ps) == len(ks)
+}
+
+//配置对象的订阅者，配置修改后按修改的顺序通知
+type watchHub struct {
+	subs map[*subscriber]struct{}
+	sync.Mutex
+}
+
+type subscriber struct {
+	match  func(Event) bool
+	queue  []Event
+	signal chan struct{}
+	sync.Mutex
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{subs: make(map[*subscriber]struct{})}
+}
+
+func (h *watchHub) active() bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.subs) > 0
+}
+
+//将事件放入订阅者的队列，不等待订阅者处理，因此在写锁中调用也不会阻塞写入
+func (h *watchHub) publish(events []Event) {
+	h.Lock()
+	defer h.Unlock()
+	for sub := range h.subs {
+		sub.Lock()
+		n := len(sub.queue)
+		for _, e := range events {
+			if sub.match(e) {
+				sub.queue = append(sub.queue, e)
+			}
+		}
+		if len(sub.queue) > n {
+			select {
+			case sub.signal <- struct{}{}:
+			default:
+			}
+		}
+		sub.Unlock()
+	}
+}
+
+//订阅匹配match的事件，每个订阅者在单独的goroutine中依次调用deliver，deliver返回false或者ctx结束时取消订阅并调用done
+func (h *watchHub) subscribe(ctx context.Context, match func(Event) bool, deliver func(Event) bool, done func()) {
+	sub := &subscriber{match: match, signal: make(chan struct{}, 1)}
+	h.Lock()
+	h.subs[sub] = struct{}{}
+	h.Unlock()
+
+	go func() {
+		defer done()
+		defer func() {
+			h.Lock()
+			delete(h.subs, sub)
+			h.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.signal:
+			}
+			sub.Lock()
+			events := sub.queue
+			sub.queue = nil
+			sub.Unlock()
+			for _, e := range events {
+				if ctx.Err() != nil || !deliver(e) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+//返回匹配keyPattern的配置项的变化，ctx结束时关闭channel
+func (h *watchHub) watch(ctx context.Context, keyPattern string, o *Options) <-chan Event {
+	ch := make(chan Event)
+	h.subscribe(ctx, func(e Event) bool {
+		return matchPattern(keyPattern, e.Key, o)
+	}, func(e Event) bool {
+		select {
+		case ch <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() {
+		close(ch)
+	})
+	return ch
+}
+
+//section下的配置项变化时调用fn，section为空时表示DEFAULT_SECTION
+func (h *watchHub) onChange(ctx context.Context, section string, fn func(Event), o *Options) {
+	if len(section) == 0 {
+		section = o.DefaultSection
+	}
+	h.subscribe(ctx, func(e Event) bool {
+		return o.equal(e.Section, section)
+	}, func(e Event) bool {
+		fn(e)
+		return true
+	}, func() {})
+}
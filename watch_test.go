@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Error("no event received")
+		return Event{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, name := range []string{"ini", "json"} {
+		config, err := NewConfig(name, "my."+name)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := config.Watch(ctx, "mysql::*")
+
+		config.Set("float", "1.5")
+		config.Set("mysql::port", "3307")
+		config.Delete("mysql::passwd")
+		config.Set("mysql::timeout", "5s")
+
+		want := []Event{
+			{Type: EventUpdated, Section: "mysql", Key: "mysql::port", OldValue: "3306", NewValue: "3307"},
+			{Type: EventDeleted, Section: "mysql", Key: "mysql::passwd", OldValue: "root"},
+			{Type: EventCreated, Section: "mysql", Key: "mysql::timeout", NewValue: "5s"},
+		}
+		for _, w := range want {
+			if e := nextEvent(t, ch); e != w {
+				t.Errorf("%s: got %+v, want %+v", name, e, w)
+			}
+		}
+
+		cancel()
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Error(name, "unexpected event after cancel")
+			}
+		case <-time.After(time.Second):
+			t.Error(name, "channel not closed after cancel")
+		}
+	}
+}
+
+func TestOnChangeReload(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.ini")
+	if err := ioutil.WriteFile(filename, []byte("debug = false\n[mysql]\nport = 3306\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	config, err := NewConfig("ini", filename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 10)
+	config.OnChange(ctx, "MySQL", func(e Event) {
+		events <- e
+	})
+	all := config.Watch(ctx, "**")
+
+	if err = ioutil.WriteFile(filename, []byte("debug = true\n[mysql]\nport = 3307\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.Reload(); err != nil {
+		t.Error(err)
+		return
+	}
+	if val, err := config.Int("mysql::port"); err != nil || val != 3307 {
+		t.Error("reload failed:", val, err)
+	}
+	if e := nextEvent(t, events); e.Key != "mysql::port" || e.OldValue != "3306" || e.NewValue != "3307" {
+		t.Errorf("OnChange got %+v", e)
+	}
+	if e := nextEvent(t, all); e.Key != "debug" || e.Section != "default" || e.NewValue != "true" {
+		t.Errorf("Watch got %+v", e)
+	}
+
+	//reload后保存不算冲突
+	config.Set("debug", "false")
+	if err = config.SaveConfigFile(filename); err != nil {
+		t.Error(err)
+	}
+	if err = ioutil.WriteFile(filename, []byte("[mysql\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = config.Reload(); err == nil || config.String("debug") != "false" {
+		t.Error("invalid file should not replace config:", err)
+	}
+	if err = config.Snapshot().Reload(); err != ErrReadOnly {
+		t.Error("snapshot should not reload:", err)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	o := newOptions()
+	cases := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"mysql::*", "mysql::port", true},
+		{"MySQL::port", "mysql::PORT", true},
+		{"mysql::*", "mysql::slaves::0", false},
+		{"mysql::**", "mysql::slaves::0", true},
+		{"*::port", "redis::port", true},
+		{"port", "port", true},
+		{"port", "mysql::port", false},
+	}
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.key, &o); got != c.want {
+			t.Errorf("match %q %q got %v", c.pattern, c.key, got)
+		}
+	}
+}